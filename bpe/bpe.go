@@ -0,0 +1,165 @@
+// Package bpe learns byte pair encoding merges from tokenized text and
+// splits words into subwords with them.
+//
+// Merges are stored one per line as two space separated symbols in the
+// order they were learned. The last symbol of a word ends with EndOfWord
+// and encoded subwords but the last one of a word end with Continuation,
+// as subword-nmt does, so decoding is removing Continuation followed by
+// space.
+package bpe
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// EndOfWord marks the last symbol of a word.
+const EndOfWord = "</w>"
+
+// Continuation marks subwords followed by other subwords of the same word.
+const Continuation = "@@"
+
+// Pair is a pair of adjacent symbols.
+type Pair struct {
+	Left, Right string
+}
+
+// Model is a list of merges.
+type Model struct {
+	Merges []Pair
+	ranks  map[Pair]int
+}
+
+// NewModel returns model of merges applied in given order.
+func NewModel(merges []Pair) *Model {
+	m := &Model{Merges: merges, ranks: make(map[Pair]int, len(merges))}
+	for i, merge := range merges {
+		if _, ok := m.ranks[merge]; !ok {
+			m.ranks[merge] = i
+		}
+	}
+	return m
+}
+
+// fields splits line into tokens separated by spaces, tabs and line
+// ends, other spaces like no-break space in 1 000 belong to tokens.
+func fields(line string) []string {
+	return strings.FieldsFunc(line, func(r rune) bool { return r == ' ' || r == '\t' || r == '\r' || r == '\n' })
+}
+
+// Count returns frequencies of space separated tokens read from r.
+func Count(r io.Reader) (map[string]int, error) {
+	counts := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		for _, word := range fields(scanner.Text()) {
+			counts[word]++
+		}
+	}
+	return counts, scanner.Err()
+}
+
+// symbols splits word into chars, the last one ends with EndOfWord.
+func symbols(word string) []string {
+	var s []string
+	for _, r := range word {
+		s = append(s, string(r))
+	}
+	if len(s) > 0 {
+		s[len(s)-1] += EndOfWord
+	}
+	return s
+}
+
+// Encode splits word into subwords, all but the last end with Continuation.
+func (m *Model) Encode(word string) []string {
+	s := symbols(word)
+	for len(s) > 1 {
+		best, rank := -1, len(m.Merges)
+		for i := 0; i+1 < len(s); i++ {
+			if r, ok := m.ranks[Pair{s[i], s[i+1]}]; ok && r < rank {
+				best, rank = i, r
+			}
+		}
+		if best < 0 {
+			break
+		}
+		s = merge(s, m.Merges[rank])
+	}
+	for i := range s {
+		if i < len(s)-1 {
+			s[i] += Continuation
+		} else {
+			s[i] = strings.TrimSuffix(s[i], EndOfWord)
+		}
+	}
+	return s
+}
+
+// EncodeLine encodes space separated words of line.
+func (m *Model) EncodeLine(line string) string {
+	var subwords []string
+	for _, word := range fields(line) {
+		subwords = append(subwords, m.Encode(word)...)
+	}
+	return strings.Join(subwords, " ")
+}
+
+// Decode joins subwords of encoded line back into words.
+func Decode(line string) string {
+	return strings.TrimSuffix(strings.Replace(line, Continuation+" ", "", -1), Continuation)
+}
+
+// merge returns symbols with every occurrence of pair merged.
+func merge(s []string, pair Pair) []string {
+	merged := s[:0]
+	for i := 0; i < len(s); i++ {
+		if i+1 < len(s) && s[i] == pair.Left && s[i+1] == pair.Right {
+			merged = append(merged, pair.Left+pair.Right)
+			i++
+			continue
+		}
+		merged = append(merged, s[i])
+	}
+	return merged
+}
+
+// Write writes merges to w one per line.
+func (m *Model) Write(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	for _, merge := range m.Merges {
+		out.WriteString(merge.Left)
+		out.WriteString(" ")
+		out.WriteString(merge.Right)
+		out.WriteString("\n")
+	}
+	return out.Flush()
+}
+
+// Read reads merges written by Write, the first line is skipped if it is
+// a "#version" header of subword-nmt, other lines starting with # are
+// merges of symbols like "#" learned from hashtags.
+func Read(r io.Reader) (*Model, error) {
+	var merges []Pair
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if len(text) == 0 || line == 1 && strings.HasPrefix(text, "#version") {
+			continue
+		}
+		fields := strings.Split(text, " ")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("bpe: line %d: expected two symbols got %q", line, text)
+		}
+		merges = append(merges, Pair{fields[0], fields[1]})
+	}
+	if e := scanner.Err(); e != nil {
+		return nil, e
+	}
+	return NewModel(merges), nil
+}
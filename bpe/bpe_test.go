@@ -0,0 +1,103 @@
+package bpe
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTrain(t *testing.T) {
+	counts, e := Count(strings.NewReader("low low low low low lower lower newest newest newest\nnewest newest newest widest widest widest\n"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	model := Train(counts, 15, 2)
+	expected := []Pair{{"e", "s"}, {"es", "t</w>"}, {"l", "o"}, {"e", "w"}}
+	if !reflect.DeepEqual(model.Merges[:4], expected) {
+		t.Errorf("expected merges %v got %v", expected, model.Merges)
+	}
+	// vocabulary of 11 initial symbols grows by one per merge
+	if len(model.Merges) != 4 {
+		t.Errorf("expected 4 merges got %d", len(model.Merges))
+	}
+	if encoded := model.EncodeLine("lowest newer"); encoded != "lo@@ w@@ est n@@ ew@@ e@@ r" {
+		t.Errorf("unexpected encoding %q", encoded)
+	}
+	if decoded := Decode("lo@@ w@@ est n@@ ew@@ e@@ r"); decoded != "lowest newer" {
+		t.Errorf("unexpected decoding %q", decoded)
+	}
+	if len(Train(counts, 1000, 100).Merges) != 0 {
+		t.Error("expected no merges more frequent than min count")
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	model := NewModel([]Pair{{"к", "о"}, {"ко", "т</w>"}})
+	var b bytes.Buffer
+	if e := model.Write(&b); e != nil {
+		t.Fatal(e)
+	}
+	read, e := Read(&b)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !reflect.DeepEqual(read.Merges, model.Merges) {
+		t.Errorf("expected %v got %v", model.Merges, read.Merges)
+	}
+	if encoded := read.EncodeLine("кот котик"); encoded != "кот ко@@ т@@ и@@ к" {
+		t.Errorf("unexpected encoding %q", encoded)
+	}
+	if encoded := read.EncodeLine("1\u00a0000\tкот"); encoded != "1@@ \u00a0@@ 0@@ 0@@ 0 кот" {
+		t.Errorf("unexpected encoding %q", encoded)
+	}
+	if decoded := Decode("1@@ \u00a0@@ 0@@ 0@@ 0 кот"); decoded != "1\u00a0000 кот" {
+		t.Errorf("unexpected decoding %q", decoded)
+	}
+	if read, e = Read(strings.NewReader("#version: 0.2\n# t\n#t a\n")); e != nil {
+		t.Fatal(e)
+	}
+	if expected := []Pair{{"#", "t"}, {"#t", "a"}}; !reflect.DeepEqual(read.Merges, expected) {
+		t.Errorf("expected %v got %v", expected, read.Merges)
+	}
+	if _, e = Read(strings.NewReader("a b c\n")); e == nil {
+		t.Error("expected error on bad merge line")
+	}
+}
+
+func TestTrainEncodeDecode(t *testing.T) {
+	text := "#tag #tag #tag #tags\nкот кот котик\r\n#tag кот\n"
+	counts, e := Count(strings.NewReader(text))
+	if e != nil {
+		t.Fatal(e)
+	}
+	var b bytes.Buffer
+	if e = Train(counts, 100, 2).Write(&b); e != nil {
+		t.Fatal(e)
+	}
+	if !strings.HasPrefix(b.String(), "# t\n") {
+		t.Errorf("expected # t merge first got %q", b.String())
+	}
+	model, e := Read(&b)
+	if e != nil {
+		t.Fatal(e)
+	}
+	reader := bufio.NewReader(strings.NewReader(text))
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+		encoded := model.EncodeLine(line)
+		if strings.ContainsAny(encoded, "\r\n") {
+			t.Errorf("line end is encoded in %q", encoded)
+		}
+		if decoded := Decode(encoded); decoded != strings.TrimRight(line, "\r\n") {
+			t.Errorf("expected %q got %q", strings.TrimRight(line, "\r\n"), decoded)
+		}
+	}
+	if encoded := model.EncodeLine("#tag\n"); encoded != "#tag" {
+		t.Errorf("expected #tag merged got %q", encoded)
+	}
+}
@@ -0,0 +1,123 @@
+package bpe
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// word is a distinct training word split into symbols.
+type word struct {
+	symbols []string
+	count   int
+}
+
+// candidate is a pair count pushed to the queue, it is stale once the
+// count of the pair changes.
+type candidate struct {
+	pair  Pair
+	count int
+}
+
+// queue is a heap of candidates, the most frequent goes first, ties are
+// broken by symbols so that training is deterministic.
+type queue []candidate
+
+func (q queue) Len() int { return len(q) }
+func (q queue) Less(i, j int) bool {
+	if q[i].count != q[j].count {
+		return q[i].count > q[j].count
+	}
+	if q[i].pair.Left != q[j].pair.Left {
+		return q[i].pair.Left < q[j].pair.Left
+	}
+	return q[i].pair.Right < q[j].pair.Right
+}
+func (q queue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *queue) Push(x interface{}) { *q = append(*q, x.(candidate)) }
+func (q *queue) Pop() interface{} {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
+
+// trainer keeps pair counts of words, the words each pair occurs in and
+// the queue of changed counts.
+type trainer struct {
+	words   []word
+	counts  map[Pair]int
+	index   map[Pair]map[int]bool
+	queue   queue
+	changed map[Pair]bool
+}
+
+func (t *trainer) add(i, sign int) {
+	w := t.words[i]
+	for j := 0; j+1 < len(w.symbols); j++ {
+		pair := Pair{w.symbols[j], w.symbols[j+1]}
+		t.counts[pair] += sign * w.count
+		t.changed[pair] = true
+		if t.counts[pair] == 0 {
+			delete(t.counts, pair)
+		}
+		if sign > 0 {
+			if t.index[pair] == nil {
+				t.index[pair] = make(map[int]bool)
+			}
+			t.index[pair][i] = true
+		}
+	}
+}
+
+// best returns the most frequent pair.
+func (t *trainer) best() (Pair, int) {
+	for pair := range t.changed {
+		if count := t.counts[pair]; count > 0 {
+			heap.Push(&t.queue, candidate{pair, count})
+		}
+		delete(t.changed, pair)
+	}
+	for t.queue.Len() > 0 {
+		c := heap.Pop(&t.queue).(candidate)
+		if t.counts[c.pair] == c.count {
+			return c.pair, c.count
+		}
+	}
+	return Pair{}, 0
+}
+
+// Train learns merges from word counts until vocabulary of chars and
+// merged symbols reaches vocabSize or the most frequent pair occurs less
+// than minCount times.
+func Train(counts map[string]int, vocabSize, minCount int) *Model {
+	t := &trainer{counts: make(map[Pair]int), index: make(map[Pair]map[int]bool), changed: make(map[Pair]bool)}
+	vocabulary := make(map[string]bool)
+	keys := make([]string, 0, len(counts))
+	for w := range counts {
+		keys = append(keys, w)
+	}
+	sort.Strings(keys)
+	for _, w := range keys {
+		s := symbols(w)
+		for _, symbol := range s {
+			vocabulary[symbol] = true
+		}
+		t.words = append(t.words, word{symbols: s, count: counts[w]})
+		t.add(len(t.words)-1, 1)
+	}
+	var merges []Pair
+	for size := len(vocabulary); size < vocabSize; size++ {
+		pair, count := t.best()
+		if count < minCount || count == 0 {
+			break
+		}
+		merges = append(merges, pair)
+		for i := range t.index[pair] {
+			t.add(i, -1)
+			t.words[i].symbols = merge(t.words[i].symbols, pair)
+			t.add(i, 1)
+		}
+		delete(t.index, pair)
+	}
+	return NewModel(merges)
+}
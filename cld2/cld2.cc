@@ -1,9 +1,11 @@
 #include <cstddef>
+#include <stdlib.h>
 #include <string.h>
 #include <stdio.h>
 #include <string>
 
 #include "compact_lang_det.h"
+#include "encodings.h"
 #include "cld2.h"
 
 const char* DetectLang(char *data, int length) {
@@ -38,3 +40,109 @@ const char* DetectLang(char *data, int length) {
 
     return CLD2::LanguageCode(summary_lang);
 }
+
+// makeHints converts hints coming from Go, empty strings mean no hint
+static CLD2::CLDHints makeHints(DetectHints *hints) {
+    CLD2::CLDHints cldhints = {NULL, NULL, CLD2::UNKNOWN_ENCODING, CLD2::UNKNOWN_LANGUAGE};
+    if (hints == NULL) {
+        return cldhints;
+    }
+    if (hints->content_language != NULL && hints->content_language[0] != 0) {
+        cldhints.content_language_hint = hints->content_language;
+    }
+    if (hints->tld != NULL && hints->tld[0] != 0) {
+        cldhints.tld_hint = hints->tld;
+    }
+    if (hints->encoding >= 0 && hints->encoding < CLD2::NUM_ENCODINGS) {
+        cldhints.encoding_hint = hints->encoding;
+    }
+    if (hints->language != NULL && hints->language[0] != 0) {
+        cldhints.language_hint = CLD2::GetLanguageFromName(hints->language);
+    }
+    return cldhints;
+}
+
+// detect skips HTML tags and expands entities itself unless is_plain_text is set
+static CLD2::Language detect(char *data, int length, bool is_plain_text, DetectHints *hints,
+        DetectResult *result, CLD2::ResultChunkVector *resultchunkvector) {
+    CLD2::CLDHints cldhints = makeHints(hints);
+    int flags = 0;
+    CLD2::Language language3[3];
+    int percent3[3];
+    double normalized_score3[3];
+    int text_bytes;
+    bool is_reliable;
+
+    if (length < 0) {
+        length = strlen(data);
+    }
+
+    CLD2::Language summary_lang = CLD2::ExtDetectLanguageSummary(data,
+            length,
+            is_plain_text,
+            &cldhints,
+            flags,
+            language3,
+            percent3,
+            normalized_score3,
+            resultchunkvector,
+            &text_bytes,
+            &is_reliable);
+
+    if (result != NULL) {
+        result->language = CLD2::LanguageCode(summary_lang);
+        for (int i = 0; i < 3; i++) {
+            result->language3[i] = CLD2::LanguageCode(language3[i]);
+            result->percent3[i] = percent3[i];
+            result->normalized_score3[i] = normalized_score3[i];
+        }
+        result->text_bytes = text_bytes;
+        result->is_reliable = is_reliable ? 1 : 0;
+    }
+    return summary_lang;
+}
+
+void DetectLangFull(char *data, int length, int is_plain_text, DetectHints *hints, DetectResult *result) {
+    detect(data, length, is_plain_text != 0, hints, result, NULL);
+}
+
+int DetectLangChunks(char *data, int length, int is_plain_text, DetectHints *hints, DetectChunk **chunks) {
+    CLD2::ResultChunkVector resultchunkvector;
+
+    detect(data, length, is_plain_text != 0, hints, NULL, &resultchunkvector);
+
+    *chunks = NULL;
+    int n = resultchunkvector.size();
+    if (n == 0) {
+        return 0;
+    }
+    // caller owns the array and must free it
+    *chunks = (DetectChunk*)malloc(n * sizeof(DetectChunk));
+    if (*chunks == NULL) {
+        return 0;
+    }
+    for (int i = 0; i < n; i++) {
+        const CLD2::ResultChunk& rc = resultchunkvector[i];
+        (*chunks)[i].offset = rc.offset;
+        (*chunks)[i].bytes = rc.bytes;
+        (*chunks)[i].language = CLD2::LanguageCode(static_cast<CLD2::Language>(rc.lang1));
+    }
+    return n;
+}
+
+int LanguageCount() {
+    return CLD2::NUM_LANGUAGES;
+}
+
+const char* LanguageCodeAt(int i) {
+    return CLD2::LanguageCode(static_cast<CLD2::Language>(i));
+}
+
+const char* LanguageNameAt(int i) {
+    return CLD2::LanguageName(static_cast<CLD2::Language>(i));
+}
+
+// LanguageCodeFromName accepts full names, codes and language-script-region tags
+const char* LanguageCodeFromName(const char *name) {
+    return CLD2::LanguageCode(CLD2::GetLanguageFromName(name));
+}
@@ -9,7 +9,76 @@ package cld2
 // #include <stdlib.h>
 // #include "cld2.h"
 import "C"
-import "unsafe"
+import (
+	"errors"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// ErrInvalidUTF8 is returned when the text given for detection
+// is not valid UTF-8, CLD2 only accepts interchange-valid UTF-8.
+var ErrInvalidUTF8 = errors.New("cld2: text is not valid utf8")
+
+// Language is one of the top three candidate languages
+// reported by CLD2 for a text.
+type Language struct {
+	// Code is the CLD2 language code, "un" for unknown.
+	Code string
+	// Percent is the share 0..100 of the text bytes in this language.
+	Percent int
+	// Score is the ratio to the normal score for real text in this
+	// language, values far from 1.0 indicate skewed text or gibberish.
+	Score float64
+}
+
+// Result holds the full output of CLD2 language detection.
+type Result struct {
+	// Code is the summary language code, the same as returned by Detect.
+	Code string
+	// Languages are the top three candidate languages, best first.
+	Languages [3]Language
+	// TextBytes is the number of letter bytes actually scored.
+	TextBytes int
+	// Reliable is set when the summary language is clearly more
+	// probable than the second best one.
+	Reliable bool
+}
+
+// Top returns the candidate for the summary language, the best
+// candidate if the summary language is not among them.
+func (r Result) Top() Language {
+	for _, language := range r.Languages {
+		if language.Code == r.Code {
+			return language
+		}
+	}
+	return r.Languages[0]
+}
+
+// Chunk is a span of the input text detected as a single language.
+type Chunk struct {
+	// Offset is the starting byte offset of the chunk in the text.
+	Offset int
+	// Length is the number of bytes in the chunk.
+	Length int
+	// Code is the CLD2 language code of the chunk, "un" when the
+	// span is too short or unreliable.
+	Code string
+}
+
+// Text returns the part of text covered by the chunk.
+func (c Chunk) Text(text string) string {
+	return text[c.Offset : c.Offset+c.Length]
+}
+
+// Options control how CLD2 treats the text.
+type Options struct {
+	// Hints are forwarded to CLD2.
+	Hints Hints
+	// HTML makes CLD2 skip tags, scripts and styles and expand
+	// entities itself instead of scoring the text as plain.
+	HTML bool
+}
 
 // Detect returns the language code for detected language
 // in the given text.
@@ -23,3 +92,90 @@ func Detect(text string) string {
 	}
 	return lang
 }
+
+// DetectFull returns the top three candidate languages with their
+// percents and normalized scores, the reliability flag and the
+// number of text bytes scored for the given text.
+func DetectFull(text string) (Result, error) {
+	return DetectWithHints(text, Hints{})
+}
+
+// DetectWithHints is like DetectFull but forwards the given
+// hints to CLD2.
+func DetectWithHints(text string, hints Hints) (Result, error) {
+	return DetectWithOptions(text, Options{Hints: hints})
+}
+
+// DetectWithOptions is like DetectFull but honors the given options.
+func DetectWithOptions(text string, options Options) (Result, error) {
+	if !utf8.ValidString(text) {
+		return Result{}, ErrInvalidUTF8
+	}
+	h := newCHints(options.Hints)
+	defer h.free()
+	var res C.DetectResult
+	cs := C.CString(text)
+	C.DetectLangFull(cs, C.int(len(text)), isPlainText(options), &h.hints, &res)
+	C.free(unsafe.Pointer(cs))
+	return newResult(&res), nil
+}
+
+func isPlainText(options Options) C.int {
+	if options.HTML {
+		return 0
+	}
+	return 1
+}
+
+func newResult(res *C.DetectResult) Result {
+	result := Result{
+		Code:      C.GoString(res.language),
+		TextBytes: int(res.text_bytes),
+		Reliable:  res.is_reliable != 0,
+	}
+	for i := range result.Languages {
+		result.Languages[i] = Language{
+			Code:    C.GoString(res.language3[i]),
+			Percent: int(res.percent3[i]),
+			Score:   float64(res.normalized_score3[i]),
+		}
+	}
+	return result
+}
+
+// Segment splits the given text into consecutive spans of
+// different languages.
+func Segment(text string) ([]Chunk, error) {
+	return SegmentWithOptions(text, Options{})
+}
+
+// SegmentWithOptions is like Segment but honors the given options,
+// in HTML mode chunk offsets still refer to the original text.
+func SegmentWithOptions(text string, options Options) ([]Chunk, error) {
+	if !utf8.ValidString(text) {
+		return nil, ErrInvalidUTF8
+	}
+	h := newCHints(options.Hints)
+	defer h.free()
+	var res *C.DetectChunk
+	cs := C.CString(text)
+	n := int(C.DetectLangChunks(cs, C.int(len(text)), isPlainText(options), &h.hints, &res))
+	C.free(unsafe.Pointer(cs))
+	return newChunks(res, n), nil
+}
+
+func newChunks(res *C.DetectChunk, n int) []Chunk {
+	if res == nil {
+		return nil
+	}
+	defer C.free(unsafe.Pointer(res))
+	chunks := make([]Chunk, 0, n)
+	for _, c := range unsafe.Slice(res, n) {
+		chunks = append(chunks, Chunk{
+			Offset: int(c.offset),
+			Length: int(c.bytes),
+			Code:   C.GoString(c.language),
+		})
+	}
+	return chunks
+}
@@ -2,7 +2,36 @@
 extern "C" {
 #endif
 
+typedef struct {
+    const char* content_language;
+    const char* tld;
+    int encoding;
+    const char* language;
+} DetectHints;
+
+typedef struct {
+    const char* language;
+    const char* language3[3];
+    int percent3[3];
+    double normalized_score3[3];
+    int text_bytes;
+    int is_reliable;
+} DetectResult;
+
+typedef struct {
+    int offset;
+    int bytes;
+    const char* language;
+} DetectChunk;
+
 const char* DetectLang(char *data, int length);
+void DetectLangFull(char *data, int length, int is_plain_text, DetectHints *hints, DetectResult *result);
+int DetectLangChunks(char *data, int length, int is_plain_text, DetectHints *hints, DetectChunk **chunks);
+
+int LanguageCount();
+const char* LanguageCodeAt(int i);
+const char* LanguageNameAt(int i);
+const char* LanguageCodeFromName(const char *name);
 
 #ifdef __cplusplus
 }
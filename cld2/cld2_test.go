@@ -0,0 +1,108 @@
+package cld2
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const (
+	english = "The quick brown fox jumps over the lazy dog while the farmer watches from the porch of his old wooden house. "
+	russian = "Мама мыла раму, а папа читал газету и пил горячий чай на кухне. "
+)
+
+// requireTables skips the test if CLD2 scoring tables are not linked,
+// as in builds made with stubbed quadgram tables.
+func requireTables(t *testing.T) {
+	if result, e := DetectFull(strings.Repeat(english, 3)); e != nil || result.Code != "en" {
+		t.Skip("CLD2 scoring tables are not linked")
+	}
+}
+
+// language returns candidate with the given code or zero one.
+func language(result Result, code string) Language {
+	for _, language := range result.Languages {
+		if language.Code == code {
+			return language
+		}
+	}
+	return Language{}
+}
+
+func TestDetectInvalidUTF8(t *testing.T) {
+	if _, e := DetectFull("bad \xff text"); e != ErrInvalidUTF8 {
+		t.Errorf("expected %v got %v", ErrInvalidUTF8, e)
+	}
+	if _, e := DetectWithOptions("bad \xff text", Options{HTML: true}); e != ErrInvalidUTF8 {
+		t.Errorf("expected %v got %v", ErrInvalidUTF8, e)
+	}
+}
+
+func TestDetectFull(t *testing.T) {
+	requireTables(t)
+	result, e := DetectFull(strings.Repeat(english, 3))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !result.Reliable || result.Top().Code != "en" || result.Top().Percent < 90 || result.Top().Score <= 0 {
+		t.Errorf("expected reliable english got %+v", result)
+	}
+	text := strings.Repeat(english, 3) + russian
+	if result, e = DetectFull(text); e != nil {
+		t.Fatal(e)
+	}
+	en, ru := language(result, "en"), language(result, "ru")
+	if result.Code != "en" || result.Top() != en {
+		t.Errorf("expected english summary got %+v", result)
+	}
+	if ru.Percent <= 0 || en.Percent <= ru.Percent || en.Percent+ru.Percent > 100 {
+		t.Errorf("expected english and less russian got %+v", result)
+	}
+	if en.Score <= 0 || ru.Score <= 0 {
+		t.Errorf("expected positive scores got %+v", result)
+	}
+	if result.TextBytes <= 0 || result.TextBytes > len(text) {
+		t.Errorf("unexpected number of scored bytes %d of %d", result.TextBytes, len(text))
+	}
+}
+
+func TestDetectWithHints(t *testing.T) {
+	requireTables(t)
+	// Indonesian and Malay share most words, hints decide between them.
+	text := "Saya tidak tahu apa yang harus saya lakukan dengan semua buku ini."
+	for expected, hints := range map[string]Hints{
+		"id": {ContentLanguage: "id"},
+		"ms": {ContentLanguage: "ms"},
+	} {
+		result, e := DetectWithHints(text, hints)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if result.Code != expected {
+			t.Errorf("%+v: expected %s got %+v", hints, expected, result)
+		}
+	}
+}
+
+func TestDetectHTML(t *testing.T) {
+	requireTables(t)
+	var entities strings.Builder
+	for _, r := range strings.Repeat(russian, 2) {
+		fmt.Fprintf(&entities, "&#%d;", r)
+	}
+	page := `<html><head><style>body { font-family: serif }</style></head><body>` +
+		`<div title="` + strings.Repeat(english, 2) + `">&laquo;` + entities.String() + `&raquo;&nbsp;</div></body></html>`
+	result, e := DetectWithOptions(page, Options{HTML: true})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if result.Code != "ru" || language(result, "en").Percent != 0 {
+		t.Errorf("expected russian text without english attribute got %+v", result)
+	}
+	if result, e = DetectWithOptions(page, Options{}); e != nil {
+		t.Fatal(e)
+	}
+	if result.Code == "ru" || language(result, "en").Percent == 0 {
+		t.Errorf("expected english attribute and no entities scored as plain text got %+v", result)
+	}
+}
@@ -0,0 +1,102 @@
+package cld2
+
+// #include <stdlib.h>
+// #include "cld2.h"
+import "C"
+import (
+	"strings"
+	"unsafe"
+)
+
+// Hints carry information external to the text itself,
+// passing them whenever known improves detection accuracy.
+type Hints struct {
+	// ContentLanguage is the value of an HTTP Content-Language
+	// header, "mi,en" boosts Maori and English.
+	ContentLanguage string
+	// TLD is the top level domain of the source URL, "id" boosts Indonesian.
+	TLD string
+	// Encoding is the charset the document was originally in,
+	// "shift_jis" boosts Japanese, see Encodings for known names.
+	Encoding string
+	// Language is the expected language as code or English name,
+	// "ru" or "RUSSIAN" boosts Russian.
+	Language string
+}
+
+// Encodings maps lower case charset names to CLD2 encoding numbers
+// from encodings.h.
+var Encodings = map[string]int{
+	"iso-8859-1":   0,
+	"latin1":       0,
+	"iso-8859-2":   1,
+	"iso-8859-5":   4,
+	"iso-8859-6":   5,
+	"iso-8859-7":   6,
+	"iso-8859-8":   7,
+	"iso-8859-9":   8,
+	"euc-jp":       10,
+	"shift_jis":    11,
+	"sjis":         11,
+	"iso-2022-jp":  12,
+	"big5":         13,
+	"gb2312":       14,
+	"euc-kr":       16,
+	"cp932":        21,
+	"utf-8":        22,
+	"utf8":         22,
+	"us-ascii":     24,
+	"ascii":        24,
+	"koi8-r":       25,
+	"windows-1251": 26,
+	"cp1251":       26,
+	"windows-1252": 27,
+	"cp1252":       27,
+	"koi8-u":       28,
+	"windows-1250": 29,
+	"iso-8859-15":  30,
+	"windows-1254": 31,
+	"windows-1257": 32,
+	"tis-620":      33,
+	"windows-1256": 35,
+	"windows-1255": 36,
+	"windows-1253": 41,
+	"cp866":        42,
+	"iso-8859-13":  43,
+	"gbk":          45,
+	"gb18030":      46,
+	"utf-16be":     57,
+	"utf-16le":     58,
+}
+
+// cHints holds C copies of the hint strings, free must be
+// called once detection is done.
+type cHints struct {
+	hints C.DetectHints
+}
+
+func newCHints(hints Hints) *cHints {
+	h := new(cHints)
+	h.hints.encoding = -1
+	if encoding, ok := Encodings[strings.ToLower(hints.Encoding)]; ok {
+		h.hints.encoding = C.int(encoding)
+	}
+	if len(hints.ContentLanguage) > 0 {
+		h.hints.content_language = C.CString(hints.ContentLanguage)
+	}
+	if len(hints.TLD) > 0 {
+		h.hints.tld = C.CString(strings.TrimPrefix(strings.ToLower(hints.TLD), "."))
+	}
+	if len(hints.Language) > 0 {
+		h.hints.language = C.CString(hints.Language)
+	}
+	return h
+}
+
+func (h *cHints) free() {
+	for _, s := range []*C.char{h.hints.content_language, h.hints.tld, h.hints.language} {
+		if s != nil {
+			C.free(unsafe.Pointer(s))
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package cld2
+
+// #include <stdlib.h>
+// #include "cld2.h"
+import "C"
+import (
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// LanguageName is a language known to CLD2.
+type LanguageName struct {
+	Code string
+	Name string
+}
+
+var (
+	languagesOnce sync.Once
+	languages     []LanguageName
+	// byName maps lower case names and codes to CLD2 codes,
+	// codes win over names like "GA" (Ga) and "ga" (Irish)
+	byName map[string]string
+)
+
+func loadLanguages() {
+	n := int(C.LanguageCount())
+	languages = make([]LanguageName, 0, n)
+	byName = make(map[string]string, 2*n)
+	for i := 0; i < n; i++ {
+		language := LanguageName{
+			Code: C.GoString(C.LanguageCodeAt(C.int(i))),
+			Name: C.GoString(C.LanguageNameAt(C.int(i))),
+		}
+		if language.Code == "" { // unused slot of the enum
+			continue
+		}
+		languages = append(languages, language)
+		byName[nameKey(language.Name)] = language.Code
+	}
+	for _, language := range languages {
+		byName[nameKey(language.Code)] = language.Code
+	}
+}
+
+func nameKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return '_'
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(name)))
+}
+
+// Languages returns all languages CLD2 knows.
+func Languages() []LanguageName {
+	languagesOnce.Do(loadLanguages)
+	return languages
+}
+
+// LanguageCode maps an ISO 639-1 or 639-3 code, a BCP-47 tag or an
+// English language name to the CLD2 language code, "ukr", "uk-UA" and
+// "Ukrainian" all give "uk". Empty string is returned for unknown names.
+func LanguageCode(name string) string {
+	languagesOnce.Do(loadLanguages)
+	if code, ok := byName[nameKey(name)]; ok {
+		return code
+	}
+	parts := strings.FieldsFunc(strings.TrimSpace(name), func(r rune) bool {
+		return r == '-' || r == '_'
+	})
+	if len(parts) == 0 {
+		return ""
+	}
+	for i, part := range parts {
+		switch {
+		case i == 0:
+			parts[i] = strings.ToLower(part)
+			if code, ok := iso639_3[parts[i]]; ok {
+				parts[i] = code
+			}
+		case len(part) == 4: // script
+			parts[i] = strings.ToUpper(part[:1]) + strings.ToLower(part[1:])
+		default: // region
+			parts[i] = strings.ToUpper(part)
+		}
+	}
+	tag := strings.Join(parts, "-")
+	cs := C.CString(tag)
+	code := C.GoString(C.LanguageCodeFromName(cs))
+	C.free(unsafe.Pointer(cs))
+	if code == "un" && parts[0] != "un" {
+		return ""
+	}
+	return code
+}
+
+// iso639_3 maps ISO 639-3 and 639-2/B codes to ISO 639-1 codes
+// used by CLD2.
+var iso639_3 = map[string]string{
+	"aar": "aa", "abk": "ab", "afr": "af", "aka": "ak", "amh": "am",
+	"ara": "ar", "asm": "as", "aym": "ay", "aze": "az", "bak": "ba",
+	"bel": "be", "bul": "bg", "bih": "bh", "bis": "bi", "ben": "bn",
+	"bod": "bo", "tib": "bo", "bre": "br", "bos": "bs", "cat": "ca",
+	"cos": "co", "ces": "cs", "cze": "cs", "cym": "cy", "wel": "cy",
+	"dan": "da", "deu": "de", "ger": "de", "div": "dv", "dzo": "dz",
+	"ewe": "ee", "ell": "el", "gre": "el", "eng": "en", "epo": "eo",
+	"spa": "es", "est": "et", "eus": "eu", "baq": "eu", "fas": "fa",
+	"per": "fa", "fin": "fi", "fij": "fj", "fao": "fo", "fra": "fr",
+	"fre": "fr", "fry": "fy", "gle": "ga", "gla": "gd", "glg": "gl",
+	"grn": "gn", "guj": "gu", "glv": "gv", "hau": "ha", "heb": "he",
+	"hin": "hi", "hrv": "hr", "hat": "ht", "hun": "hu", "hye": "hy",
+	"arm": "hy", "ina": "ia", "ind": "id", "ile": "ie", "ibo": "ig",
+	"ipk": "ik", "isl": "is", "ice": "is", "ita": "it", "iku": "iu",
+	"jpn": "ja", "jav": "jv", "kat": "ka", "geo": "ka", "kaz": "kk",
+	"kal": "kl", "khm": "km", "kan": "kn", "kor": "ko", "kas": "ks",
+	"kur": "ku", "kir": "ky", "lat": "la", "ltz": "lb", "lug": "lg",
+	"lin": "ln", "lao": "lo", "lit": "lt", "lav": "lv", "mlg": "mg",
+	"mri": "mi", "mao": "mi", "mkd": "mk", "mac": "mk", "mal": "ml",
+	"mon": "mn", "mar": "mr", "msa": "ms", "may": "ms", "mlt": "mt",
+	"mya": "my", "bur": "my", "nau": "na", "nep": "ne", "nld": "nl",
+	"dut": "nl", "nno": "nn", "nor": "no", "nob": "nb", "nya": "ny",
+	"oci": "oc", "orm": "om", "ori": "or", "oss": "os", "pan": "pa",
+	"pol": "pl", "pus": "ps", "por": "pt", "que": "qu", "roh": "rm",
+	"run": "rn", "ron": "ro", "rum": "ro", "rus": "ru", "kin": "rw",
+	"san": "sa", "snd": "sd", "sag": "sg", "sin": "si", "slk": "sk",
+	"slo": "sk", "slv": "sl", "smo": "sm", "sna": "sn", "som": "so",
+	"sqi": "sq", "alb": "sq", "srp": "sr", "ssw": "ss", "sot": "st",
+	"sun": "su", "swe": "sv", "swa": "sw", "tam": "ta", "tel": "te",
+	"tgk": "tg", "tha": "th", "tir": "ti", "tuk": "tk", "tgl": "tl",
+	"fil": "tl", "tsn": "tn", "ton": "to", "tur": "tr", "tso": "ts",
+	"tat": "tt", "twi": "tw", "uig": "ug", "ukr": "uk", "urd": "ur",
+	"uzb": "uz", "ven": "ve", "vie": "vi", "vol": "vo", "wol": "wo",
+	"xho": "xh", "yid": "yi", "yor": "yo", "zha": "za", "zho": "zh",
+	"chi": "zh", "zul": "zu", "nbl": "nr",
+}
@@ -0,0 +1,22 @@
+package cld2
+
+import "testing"
+
+func TestLanguageCode(t *testing.T) {
+	for name, code := range map[string]string{
+		"ru":               "ru",
+		"rus":              "ru",
+		"Russian":          "ru",
+		"uk-UA":            "uk",
+		"ukr":              "uk",
+		"kk_KZ":            "kk",
+		"zh-TW":            "zh-Hant",
+		"Mauritian Creole": "mfe",
+		"ga":               "ga",
+		"klingonx":         "",
+	} {
+		if got := LanguageCode(name); got != code {
+			t.Errorf("LanguageCode(%q) = %q, expected %q", name, got, code)
+		}
+	}
+}
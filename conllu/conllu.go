@@ -0,0 +1,163 @@
+// Package conllu reads and writes CoNLL-U files, the format of Universal
+// Dependencies treebanks and of UDPipe output, as udpipe sentences.
+//
+// Comments, multiword tokens, empty nodes and DEPS and MISC columns are
+// kept, so a file read and written back is unchanged as long as its
+// features are sorted as UD requires.
+package conllu
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/vseledkin/gorpora/udpipe"
+)
+
+// maxLineSize limits the size of a line in CoNLL-U file.
+const maxLineSize = 16 * 1024 * 1024
+
+// Reader reads sentences from CoNLL-U stream.
+type Reader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewReader returns reader of CoNLL-U sentences from r.
+func NewReader(r io.Reader) *Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	return &Reader{scanner: scanner}
+}
+
+// Read returns the next sentence, io.EOF is returned when there are no
+// more sentences.
+func (r *Reader) Read() (*udpipe.Sentence, error) {
+	var sentence *udpipe.Sentence
+	for r.scanner.Scan() {
+		r.line++
+		line := strings.TrimRight(r.scanner.Text(), "\r")
+		if len(line) == 0 {
+			if sentence != nil {
+				return sentence, nil
+			}
+			continue // skip extra blank lines
+		}
+		if sentence == nil {
+			sentence = new(udpipe.Sentence)
+		}
+		if strings.HasPrefix(line, "#") {
+			sentence.Comments = append(sentence.Comments, line)
+			if value, ok := commentValue(line, "sent_id"); ok {
+				if id, e := strconv.Atoi(value); e == nil {
+					sentence.ID = id
+				}
+			} else if value, ok := commentValue(line, "text"); ok {
+				sentence.Body = value
+			}
+			continue
+		}
+		token, e := udpipe.ParseToken(line)
+		if e != nil {
+			return nil, fmt.Errorf("conllu: line %d: %v", r.line, e)
+		}
+		sentence.AddToken(token)
+	}
+	if e := r.scanner.Err(); e != nil {
+		return nil, e
+	}
+	if sentence != nil {
+		return sentence, nil
+	}
+	return nil, io.EOF
+}
+
+// commentValue returns value of "# key = value" comment.
+func commentValue(line, key string) (string, bool) {
+	prefix := "# " + key + " ="
+	if !strings.HasPrefix(line, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(line[len(prefix):], " "), true
+}
+
+// ReadAll reads all sentences from r.
+func ReadAll(r io.Reader) (sentences []*udpipe.Sentence, e error) {
+	reader := NewReader(r)
+	for {
+		sentence, e := reader.Read()
+		if e == io.EOF {
+			return sentences, nil
+		}
+		if e != nil {
+			return sentences, e
+		}
+		sentences = append(sentences, sentence)
+	}
+}
+
+// Writer writes sentences to CoNLL-U stream.
+type Writer struct {
+	w *bufio.Writer
+}
+
+// NewWriter returns writer of CoNLL-U sentences to w, Flush must be
+// called when done.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Write writes the sentence. Sentences read from CoNLL-U keep their
+// comments with "# text" updated from Body, others get "# sent_id"
+// and "# text" comments made of ID and Body.
+func (w *Writer) Write(sentence *udpipe.Sentence) error {
+	if sentence.Comments == nil {
+		if sentence.ID > 0 {
+			fmt.Fprintf(w.w, "# sent_id = %d\n", sentence.ID)
+		}
+		if len(sentence.Body) > 0 {
+			fmt.Fprintf(w.w, "# text = %s\n", sentence.Body)
+		}
+	}
+	for _, comment := range sentence.Comments {
+		if _, ok := commentValue(comment, "text"); ok {
+			comment = "# text = " + sentence.Body
+		}
+		w.w.WriteString(comment)
+		w.w.WriteString("\n")
+	}
+	multiwords, emptyNodes := sentence.Multiwords, sentence.EmptyNodes
+	// empty nodes like 0.1 precede the first word
+	for len(emptyNodes) > 0 && emptyNodes[0].ID == 0 {
+		w.writeToken(emptyNodes[0])
+		emptyNodes = emptyNodes[1:]
+	}
+	for _, token := range sentence.Tokens {
+		for len(multiwords) > 0 && multiwords[0].ID <= token.ID {
+			w.writeToken(multiwords[0])
+			multiwords = multiwords[1:]
+		}
+		w.writeToken(token)
+		for len(emptyNodes) > 0 && emptyNodes[0].ID <= token.ID {
+			w.writeToken(emptyNodes[0])
+			emptyNodes = emptyNodes[1:]
+		}
+	}
+	for _, token := range append(multiwords, emptyNodes...) {
+		w.writeToken(token)
+	}
+	_, e := w.w.WriteString("\n")
+	return e
+}
+
+func (w *Writer) writeToken(token *udpipe.Token) {
+	w.w.WriteString(token.String())
+	w.w.WriteString("\n")
+}
+
+// Flush writes buffered data to the underlying writer.
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
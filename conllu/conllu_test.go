@@ -0,0 +1,46 @@
+package conllu
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	data, e := ioutil.ReadFile("testdata/sample.conllu")
+	if e != nil {
+		t.Fatal(e)
+	}
+	sentences, e := ReadAll(bytes.NewReader(data))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(sentences) != 2 {
+		t.Fatalf("expected 2 sentences got %d", len(sentences))
+	}
+	first := sentences[0]
+	if first.Body != "Vámonos al mar." || len(first.Tokens) != 6 || len(first.Multiwords) != 2 {
+		t.Fatalf("unexpected first sentence %#v", first)
+	}
+	if first.Tokens[4].Misc != "SpaceAfter=No" || first.Tokens[1].Features["Case"] != "Acc,Dat" {
+		t.Fatalf("unexpected token %#v", first.Tokens[4])
+	}
+	second := sentences[1]
+	if second.ID != 2 || len(second.EmptyNodes) != 1 || second.EmptyNodes[0].Dependency != -1 {
+		t.Fatalf("unexpected second sentence %#v", second)
+	}
+
+	var out bytes.Buffer
+	writer := NewWriter(&out)
+	for _, sentence := range sentences {
+		if e = writer.Write(sentence); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if e = writer.Flush(); e != nil {
+		t.Fatal(e)
+	}
+	if out.String() != string(data) {
+		t.Fatalf("round trip mismatch:\n%s\nexpected:\n%s", out.String(), data)
+	}
+}
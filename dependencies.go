@@ -0,0 +1,165 @@
+package gorpora
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/vseledkin/gorpora/conllu"
+	"github.com/vseledkin/gorpora/udpipe"
+)
+
+// DependencyExtractor configures ExtractDependencies.
+type DependencyExtractor struct {
+	// N is the number of tokens in syntactic n-grams, 2 outputs
+	// head, relation, dependent triples.
+	N int
+	// Relations lists accepted relations, all are accepted if it is
+	// empty. A relation without subtype like nmod also accepts its
+	// subtypes like nmod:poss.
+	Relations []string
+	// Exclude lists rejected relations.
+	Exclude []string
+	// Words outputs word forms instead of lemmas.
+	Words bool
+	// CoNLLU tells that input is in CoNLL-U format and is not parsed.
+	CoNLLU bool
+}
+
+// relationSet returns function telling if relation is in the set.
+func relationSet(relations []string) func(string) bool {
+	set := make(map[string]bool)
+	for _, relation := range relations {
+		set[relation] = true
+	}
+	return func(relation string) bool {
+		if set[relation] {
+			return true
+		}
+		if i := strings.Index(relation, ":"); i > 0 {
+			return set[relation[:i]]
+		}
+		return false
+	}
+}
+
+// dependencyWriter writes syntactic n-grams of sentences.
+type dependencyWriter struct {
+	*DependencyExtractor
+	accept, reject func(string) bool
+	out            *bufio.Writer
+}
+
+func newDependencyWriter(w io.Writer, extractor *DependencyExtractor) (*dependencyWriter, error) {
+	if extractor.N < 2 {
+		return nil, fmt.Errorf("n-gram length must be at least 2, got %d", extractor.N)
+	}
+	return &dependencyWriter{
+		DependencyExtractor: extractor,
+		accept:              relationSet(extractor.Relations),
+		reject:              relationSet(extractor.Exclude),
+		out:                 bufio.NewWriter(w),
+	}, nil
+}
+
+func (w *dependencyWriter) relation(token *udpipe.Token) bool {
+	if len(w.Relations) > 0 && !w.accept(token.Function) {
+		return false
+	}
+	return !w.reject(token.Function)
+}
+
+func (w *dependencyWriter) word(token *udpipe.Token) string {
+	if w.Words || token.Lemma == "" || token.Lemma == "_" {
+		return token.Word
+	}
+	return token.Lemma
+}
+
+// write writes a line for every chain of N tokens going from a token up
+// to its heads, the top head goes first followed by relations and
+// dependents, e.g. head relation dependent for N equal to 2.
+func (w *dependencyWriter) write(sentence *udpipe.Sentence) error {
+	sentence.MakeDependencies()
+	path := make([]*udpipe.Token, 0, w.N)
+	for _, token := range sentence.Tokens {
+		path = path[:0]
+		for t := token; t != nil && len(path) < w.N; t = t.DependencyToken {
+			if len(path) < w.N-1 && !w.relation(t) {
+				break
+			}
+			path = append(path, t)
+		}
+		if len(path) < w.N {
+			continue
+		}
+		for i := len(path) - 1; i >= 0; i-- {
+			w.out.WriteString(w.word(path[i]))
+			if i > 0 {
+				w.out.WriteString("\t")
+				w.out.WriteString(path[i-1].Function)
+				w.out.WriteString("\t")
+			}
+		}
+		if _, e := w.out.WriteString("\n"); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// ExtractDependencies outputs syntactic n-grams of input sentences, input
+// text is parsed by udpipe unless it is already in CoNLL-U format.
+func ExtractDependencies(extractor *DependencyExtractor, options *UdpipeOptions) error {
+	writer, e := newDependencyWriter(os.Stdout, extractor)
+	if e != nil {
+		return e
+	}
+	defer writer.out.Flush()
+	if extractor.CoNLLU {
+		reader := conllu.NewReader(os.Stdin)
+		for {
+			sentence, e := reader.Read()
+			if e == io.EOF {
+				return nil
+			}
+			if e != nil {
+				return e
+			}
+			if e = writer.write(sentence); e != nil {
+				return e
+			}
+		}
+	}
+
+	reject, stop, e := startParser(options)
+	if e != nil {
+		return e
+	}
+	defer stop()
+	work := func(line string) interface{} {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			return nil
+		}
+		return parse(line, options.Timeout)
+	}
+	emit := func(line string, result interface{}) error {
+		r, ok := result.(*parsed)
+		if !ok {
+			return nil
+		}
+		if r.err != nil {
+			return skipParseError(reject, line, r.err)
+		}
+		for _, sentence := range r.sentences {
+			if e := writer.write(sentence); e != nil {
+				return e
+			}
+		}
+		return nil
+	}
+	return parallelLines(os.Stdin, options.Threads, false, work, emit)
+}
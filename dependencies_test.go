@@ -0,0 +1,39 @@
+package gorpora
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDependencyWriter(t *testing.T) {
+	sentence := testSentence(t, "Серая кошка видит мышь.",
+		"1\tСерая\tсерый\tADJ\t_\t_\t2\tamod\t_\t_",
+		"2\tкошка\tкошка\tNOUN\t_\t_\t3\tnsubj\t_\t_",
+		"3\tвидит\tвидеть\tVERB\t_\t_\t0\troot\t_\t_",
+		"4\tмышь\tмышь\tNOUN\t_\t_\t3\tobj\t_\tSpaceAfter=No",
+		"5\t.\t.\tPUNCT\t_\t_\t9\tpunct\t_\t_",
+	)
+	for _, c := range []struct {
+		extractor DependencyExtractor
+		expected  string
+	}{
+		{DependencyExtractor{N: 2}, "кошка\tamod\tсерый\nвидеть\tnsubj\tкошка\nвидеть\tobj\tмышь\n"},
+		{DependencyExtractor{N: 2, Words: true, Relations: []string{"nsubj"}}, "видит\tnsubj\tкошка\n"},
+		{DependencyExtractor{N: 2, Exclude: []string{"amod"}}, "видеть\tnsubj\tкошка\nвидеть\tobj\tмышь\n"},
+		{DependencyExtractor{N: 3}, "видеть\tnsubj\tкошка\tamod\tсерый\n"},
+		{DependencyExtractor{N: 3, Exclude: []string{"nsubj"}}, ""},
+	} {
+		var b bytes.Buffer
+		w, e := newDependencyWriter(&b, &c.extractor)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if e = w.write(sentence); e != nil {
+			t.Fatal(e)
+		}
+		w.out.Flush()
+		if b.String() != c.expected {
+			t.Errorf("%+v: expected %q got %q", c.extractor, c.expected, b.String())
+		}
+	}
+}
@@ -2,6 +2,7 @@ package gorpora
 
 import (
 	"bufio"
+	stdcontext "context"
 	"crypto/md5"
 	"encoding/hex"
 	"html"
@@ -19,9 +20,8 @@ import (
 	"io"
 	"io/ioutil"
 	"path"
-	"unicode"
 
-	"github.com/vseledkin/gorpora/cld2"
+	"github.com/vseledkin/gorpora/tokenizer"
 	"github.com/vseledkin/gorpora/udpipe"
 )
 
@@ -106,78 +106,199 @@ func StripHtml() {
 	}
 }
 
-func Split(use_udpipe, output_lemmas bool) {
-	if use_udpipe {
-		PARSER = new(udpipe.Parser)
-		PARSER.Start()
-		defer PARSER.Close()
+// Sentence splitting and tokenization engines.
+const (
+	// EngineNative is the rule based tokenizer written in Go.
+	EngineNative = "native"
+	// EngineUdpipe is the udpipe tokenizer.
+	EngineUdpipe = "udpipe"
+)
+
+// UdpipeOptions configure commands backed by udpipe.
+type UdpipeOptions struct {
+	Config udpipe.ParserConfig
+	// Threads is the number of udpipe processes.
+	Threads int
+	// Reject is a path of the file receiving lines udpipe failed to parse,
+	// such lines are only logged if it is empty.
+	Reject string
+	// Timeout limits time udpipe may spend on a line, udpipe is restarted
+	// and the line is rejected once it is exceeded. Zero means no limit.
+	Timeout time.Duration
+}
+
+// startParser starts PARSER and opens reject file of options,
+// returned function stops both.
+func startParser(options *UdpipeOptions) (reject *bufio.Writer, stop func(), e error) {
+	PARSER = udpipe.NewParserPool(options.Config, options.Threads)
+	if e = PARSER.Start(); e != nil {
+		return nil, nil, e
+	}
+	if options.Reject == "" {
+		return nil, stopParser, nil
 	}
+	f, e := os.Create(options.Reject)
+	if e != nil {
+		stopParser()
+		return nil, nil, e
+	}
+	reject = bufio.NewWriter(f)
+	return reject, func() {
+		stopParser()
+		reject.Flush()
+		f.Close()
+	}, nil
+}
 
-	reader := bufio.NewReader(os.Stdin)
-	for {
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			break
+// stopParser stops PARSER logging failed udpipe exit.
+func stopParser() {
+	if e := PARSER.Close(); e != nil {
+		log.Println(e)
+	}
+}
+
+// skipParseError logs the error and writes the line to reject file if any.
+func skipParseError(reject *bufio.Writer, line string, e error) error {
+	log.Printf("skipping line: %v\n", e)
+	if reject == nil {
+		return nil
+	}
+	_, e = reject.WriteString(line)
+	return e
+}
+
+// Split tokenizes input lines with native or udpipe engine, words
+// configures native one. Lemmas and token filters require udpipe.
+func Split(engine string, output_lemmas bool, format string, filter *TokenFilter, words *tokenizer.WordTokenizer, options *UdpipeOptions) error {
+	var work func(line string) interface{}
+	var reject *bufio.Writer
+	switch engine {
+	case EngineNative:
+		if output_lemmas || !filter.empty() {
+			return fmt.Errorf("lemmas and token filters require %s engine", EngineUdpipe)
 		}
-		line = strings.TrimSpace(line)
-		if len(line) == 0 {
-			continue
+		splitter, e := tokenizer.NewSentenceSplitter("")
+		if e != nil {
+			return e
 		}
-		if use_udpipe {
-			sentences, err := PARSER.Parse(line)
-			if err != nil {
-				log.Println(err)
-				return
+		work = func(line string) interface{} {
+			line = strings.TrimSpace(line)
+			if len(line) == 0 {
+				return nil
 			}
-			var tokens []string
-			for _, sentence := range sentences {
-				for _, token := range sentence.Tokens {
-					if output_lemmas {
-						tokens = append(tokens, token.Lemma)
-					} else {
-						tokens = append(tokens, token.Word)
-					}
-				}
+			return &parsed{sentences: tokenize(splitter, words, line)}
+		}
+	case EngineUdpipe:
+	default:
+		return fmt.Errorf("unknown engine %q, expected %s or %s", engine, EngineNative, EngineUdpipe)
+	}
+	writer, e := newSentenceWriter(os.Stdout, format, output_lemmas, filter)
+	if e != nil {
+		return e
+	}
+	defer writer.flush()
+	if engine == EngineUdpipe {
+		var stop func()
+		if reject, stop, e = startParser(options); e != nil {
+			return e
+		}
+		defer stop()
+		work = func(line string) interface{} {
+			line = strings.TrimSpace(line)
+			if len(line) == 0 {
+				return nil
 			}
-			os.Stdout.WriteString(strings.Join(tokens, " "))
-		} else {
-			os.Stdout.WriteString(split2Tokens(line))
+			return parse(line, options.Timeout)
+		}
+	}
+
+	emit := func(line string, result interface{}) error {
+		r, ok := result.(*parsed)
+		if !ok {
+			return nil
+		}
+		if r.err != nil {
+			return skipParseError(reject, line, r.err)
 		}
-		os.Stdout.WriteString("\n")
+		return writer.write(strings.TrimSpace(line), r.sentences)
 	}
+	return parallelLines(os.Stdin, options.Threads, false, work, emit)
 }
 
-var PARSER *udpipe.Parser
+var PARSER *udpipe.ParserPool
 
-func Sentesize(min, max int) {
-	PARSER = new(udpipe.Parser)
-	PARSER.Start()
-	defer PARSER.Close()
+type parsed struct {
+	sentences []*udpipe.Sentence
+	err       error
+}
 
-	reader := bufio.NewReader(os.Stdin)
+func parse(text string, timeout time.Duration) *parsed {
+	ctx := stdcontext.Background()
+	if timeout > 0 {
+		var cancel stdcontext.CancelFunc
+		ctx, cancel = stdcontext.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	sentences, err := PARSER.ParseContext(ctx, text)
+	return &parsed{sentences, err}
+}
 
-	for {
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			break
+// Sentesize outputs sentences of input lines which are from min to max
+// chars long, language selects abbreviations of native engine.
+func Sentesize(engine, language string, min, max int, options *UdpipeOptions) error {
+	var work func(line string) interface{}
+	var reject *bufio.Writer
+	switch engine {
+	case EngineNative:
+		splitter, e := tokenizer.NewSentenceSplitter(language)
+		if e != nil {
+			return e
 		}
-		line = strings.TrimSpace(line)
-		if len(line) == 0 {
-			continue
+		work = func(line string) interface{} {
+			return splitter.Split(line)
 		}
-		sentences, err := PARSER.Parse(line)
-		if err != nil {
-			log.Println(err)
-			return
+	case EngineUdpipe:
+		var stop func()
+		var e error
+		if reject, stop, e = startParser(options); e != nil {
+			return e
+		}
+		defer stop()
+		work = func(line string) interface{} {
+			line = strings.TrimSpace(line)
+			if len(line) == 0 {
+				return nil
+			}
+			return parse(line, options.Timeout)
 		}
-		for _, sentence := range sentences {
-			L := utf8.RuneCountInString(sentence.Body)
-			if L >= min && L <= max {
-				os.Stdout.WriteString(sentence.Body)
-				os.Stdout.WriteString("\n")
+	default:
+		return fmt.Errorf("unknown engine %q, expected %s or %s", engine, EngineNative, EngineUdpipe)
+	}
+
+	output := func(sentence string) {
+		L := utf8.RuneCountInString(sentence)
+		if L >= min && L <= max {
+			os.Stdout.WriteString(sentence)
+			os.Stdout.WriteString("\n")
+		}
+	}
+	emit := func(line string, result interface{}) error {
+		switch r := result.(type) {
+		case []tokenizer.Span:
+			for _, sentence := range r {
+				output(sentence.Text)
+			}
+		case *parsed:
+			if r.err != nil {
+				return skipParseError(reject, line, r.err)
+			}
+			for _, sentence := range r.sentences {
+				output(sentence.Body)
 			}
 		}
+		return nil
 	}
+	return parallelLines(os.Stdin, options.Threads, false, work, emit)
 }
 
 func GetMD5Hash(bytes []byte) string {
@@ -229,58 +350,6 @@ func Unique(DEBUG bool) {
 	log.Println(lineCount-uniqueCount, "non unique lines")
 }
 
-func FilterLanguage(languages []string) {
-	reader := bufio.NewReader(os.Stdin)
-	for {
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			break
-		}
-		language := cld2.Detect(line)
-		accept := false
-		for _, lang := range languages {
-			if language == lang {
-				accept = true
-				break
-			}
-		}
-		if accept {
-			os.Stdout.WriteString(line)
-			//os.Stdout.WriteString("\n")
-		}
-	}
-}
-
-func split2Tokens(s string) string {
-	token := ""
-	var split []string
-	for _, r := range s {
-		switch {
-		case unicode.IsPunct(r) || unicode.IsSymbol(r):
-			if len(token) > 0 {
-				split = append(split, token)
-				token = ""
-			}
-			split = append(split, string(r))
-		case len(token) == 0 && unicode.IsSpace(r):
-			continue // skip leading space
-		case len(token) == 0 && !unicode.IsSpace(r):
-			token = string(r)
-		case len(token) > 0 && !unicode.IsSpace(r):
-			token += string(r)
-		case len(token) > 0 && unicode.IsSpace(r):
-			split = append(split, token)
-			token = ""
-		default:
-			panic(fmt.Errorf("unknown symbol %q", r))
-		}
-	}
-	if len(token) > 0 {
-		split = append(split, token)
-	}
-	return strings.Join(split, " ")
-}
-
 func priltLines(min, max int, r io.ReadCloser, rc *zip.ReadCloser) (collectedLineCount, filteredLineCount int) {
 	defer func() {
 		if e := r.Close(); e != nil {
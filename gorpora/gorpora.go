@@ -5,18 +5,30 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"regexp"
 
 	"github.com/vseledkin/gorpora"
 	"github.com/vseledkin/gorpora/fb2"
+	"github.com/vseledkin/gorpora/tokenizer"
+	"github.com/vseledkin/gorpora/udpipe"
 )
 
 const (
 	stripHtml             = "strip.html"
 	normalizeHtmlEntities = "normalize.html.entities"
+	normalizeText         = "normalize.text"
 	tokenize              = "word.tokenizer"
 	unique                = "unique"
 	filterLanguage        = "filter.language"
+	splitLanguage         = "split.language"
+	splitByLanguage       = "split.by.language"
+	listLanguages         = "list.languages"
 	sentences             = "sentence.tokenizer"
+	extractDependencies   = "extract.dependencies"
+	bpeTrain              = "bpe.train"
+	bpeEncode             = "bpe.encode"
+	bpeDecode             = "bpe.decode"
+	vocabulary            = "vocab"
 	fb2text               = "fb2text"
 	collect               = "collect"
 )
@@ -28,15 +40,38 @@ var (
 	MIN_LEN            int
 	MAX_COLLECT_LEN    int
 	MIN_COLLECT_LEN    int
+	MIN_PIECE_LEN      int
+	OUTPUT             string
+	GZIP               bool
 	DEBUG              bool
 	languages          arrayFlags
+	excludeLanguages   arrayFlags
+	keepPos            arrayFlags
+	dropPos            arrayFlags
+	features           arrayFlags
+	protectedPatterns  arrayFlags
+	relations          arrayFlags
+	excludeRelations   arrayFlags
 	LEMMAS             bool
 	UDPIPE             bool
+	FORMAT             string
+	ENGINE             string
+	SENTENCE_ENGINE    string
+	SENTENCE_LANGUAGE  string
 	COLLECT_INPUT      string
 	INPUT              string
 	THREADS            int
 	OUTPUT_LINE_ENDING int
 	EXTENSION          string
+	LANGUAGE_FILTER    gorpora.LanguageFilter
+	UDPIPE_OPTIONS     gorpora.UdpipeOptions
+	TOKEN_FILTER       gorpora.TokenFilter
+	DEPENDENCIES       gorpora.DependencyExtractor
+	NORMALIZATION      gorpora.Normalization
+	VOCAB_SIZE         int
+	MIN_COUNT          int
+	CODES              string
+	VOCAB              gorpora.VocabOptions
 )
 
 func (i *arrayFlags) Set(value string) error {
@@ -67,22 +102,108 @@ func main() {
 
 	stripHtmlCommand := flag.NewFlagSet(stripHtml, flag.ExitOnError)
 
+	normalizeTextCommand := flag.NewFlagSet(normalizeText, flag.ExitOnError)
+	normalizeTextCommand.StringVar(&NORMALIZATION.Form, "form", "", "Unicode normalization form, nfc or nfkc, none by default")
+	normalizeTextCommand.BoolVar(&NORMALIZATION.Invisible, "invisible", false, "remove zero-width, format and control characters")
+	normalizeTextCommand.BoolVar(&NORMALIZATION.Punctuation, "punct", false, "unify quotes to \" and ', hyphens to -, dashes to — and ellipsis to ...")
+	normalizeTextCommand.BoolVar(&NORMALIZATION.Lower, "lower", false, "lowercase text")
+	normalizeTextCommand.BoolVar(&NORMALIZATION.Yo, "yo", false, "replace ё with е")
+	normalizeTextCommand.BoolVar(&NORMALIZATION.Digits, "digits", false, "mask every digit with 0")
+	normalizeTextCommand.BoolVar(&NORMALIZATION.Space, "space", false, "collapse runs of spaces into one and trim lines")
+
 	tokenizeCommand := flag.NewFlagSet(tokenize, flag.ExitOnError)
-	tokenizeCommand.BoolVar(&UDPIPE, "udpipe", false, "use Udpipe as tokenizer")
-	tokenizeCommand.BoolVar(&LEMMAS, "lemma", false, "output lemmas instead of words")
+	tokenizeCommand.BoolVar(&UDPIPE, "udpipe", false, "use Udpipe as tokenizer, same as -engine udpipe")
+	tokenizeCommand.StringVar(&ENGINE, "engine", gorpora.EngineNative, "tokenizer: native or udpipe")
+	tokenizeCommand.Var(&protectedPatterns, "protect", "regular expression of tokens native tokenizer must not split, e.g. #\\pL+")
+	tokenizeCommand.BoolVar(&LEMMAS, "lemma", false, "output lemmas instead of words, requires udpipe engine")
+	tokenizeCommand.Var(&keepPos, "keep-pos", "POS tags of output tokens, e.g. NOUN,VERB,ADJ, requires udpipe engine")
+	tokenizeCommand.Var(&dropPos, "drop-pos", "POS tags of tokens not to output, e.g. PUNCT, requires udpipe engine")
+	tokenizeCommand.Var(&features, "feature", "feature output tokens must have as Name=Value or Name, e.g. Case=Nom, requires udpipe engine")
+	tokenizeCommand.BoolVar(&TOKEN_FILTER.WithPos, "with-pos", false, "append POS tag to output tokens, e.g. кошка_NOUN, requires udpipe engine")
+	tokenizeCommand.StringVar(&FORMAT, "format", gorpora.FormatText, "output format: text, conllu, jsonl or tsv, only udpipe engine fills lemma, POS, features and dependencies")
 	tokenizeCommand.BoolVar(&DEBUG, "debug", false, "do nothing only print use cases")
+	tokenizeCommand.DurationVar(&UDPIPE_OPTIONS.Config.LoadTimeout, "udpipe-load-timeout", udpipe.DefaultLoadTimeout, "maximum time udpipe may spend loading the model, it is killed once it is exceeded")
+	tokenizeCommand.StringVar(&UDPIPE_OPTIONS.Config.Binary, "udpipe-bin", "", "path of udpipe executable, ./udpipe/udpipe_<os>_<arch> by default")
+	tokenizeCommand.StringVar(&UDPIPE_OPTIONS.Config.Model, "udpipe-model", "", "path of udpipe model, "+udpipe.DefaultModel+" by default")
+	tokenizeCommand.IntVar(&UDPIPE_OPTIONS.Threads, "t", 1, "number of parallel tokenizers, with udpipe engine number of udpipe processes")
+	tokenizeCommand.StringVar(&UDPIPE_OPTIONS.Reject, "reject", "", "file to write lines udpipe failed to parse, they are only logged by default")
+	tokenizeCommand.DurationVar(&UDPIPE_OPTIONS.Timeout, "timeout", 0, "maximum time udpipe may spend on a line, e.g. 30s, the line is rejected and udpipe restarted once it is exceeded")
 
 	sentenceCommand := flag.NewFlagSet(sentences, flag.ExitOnError)
 	sentenceCommand.IntVar(&MAX_LEN, "max", 1000000, "maximum sentence length in chars")
 	sentenceCommand.IntVar(&MIN_LEN, "min", 10, "minimun sentence length in chars")
 	sentenceCommand.BoolVar(&DEBUG, "debug", false, "do nothing only print use cases")
+	sentenceCommand.StringVar(&SENTENCE_ENGINE, "engine", gorpora.EngineUdpipe, "sentence splitter: udpipe or native")
+	sentenceCommand.StringVar(&SENTENCE_LANGUAGE, "lang", "", "language of abbreviations of native splitter, ru or en, all by default")
+	sentenceCommand.DurationVar(&UDPIPE_OPTIONS.Config.LoadTimeout, "udpipe-load-timeout", udpipe.DefaultLoadTimeout, "maximum time udpipe may spend loading the model, it is killed once it is exceeded")
+	sentenceCommand.StringVar(&UDPIPE_OPTIONS.Config.Binary, "udpipe-bin", "", "path of udpipe executable, ./udpipe/udpipe_<os>_<arch> by default")
+	sentenceCommand.StringVar(&UDPIPE_OPTIONS.Config.Model, "udpipe-model", "", "path of udpipe model, "+udpipe.DefaultModel+" by default")
+	sentenceCommand.IntVar(&UDPIPE_OPTIONS.Threads, "t", 1, "number of parallel splitters, with -engine udpipe number of udpipe processes")
+	sentenceCommand.StringVar(&UDPIPE_OPTIONS.Reject, "reject", "", "file to write lines udpipe failed to parse, they are only logged by default")
+	sentenceCommand.DurationVar(&UDPIPE_OPTIONS.Timeout, "timeout", 0, "maximum time udpipe may spend on a line, e.g. 30s, the line is rejected and udpipe restarted once it is exceeded")
+
+	dependenciesCommand := flag.NewFlagSet(extractDependencies, flag.ExitOnError)
+	dependenciesCommand.IntVar(&DEPENDENCIES.N, "n", 2, "number of tokens in syntactic n-grams, 2 outputs head, relation, dependent triples")
+	dependenciesCommand.Var(&relations, "rel", "accepted relation, e.g. nsubj, relation without subtype also accepts its subtypes")
+	dependenciesCommand.Var(&excludeRelations, "exclude-rel", "rejected relation, e.g. punct")
+	dependenciesCommand.BoolVar(&DEPENDENCIES.Words, "word", false, "output words instead of lemmas")
+	dependenciesCommand.BoolVar(&DEPENDENCIES.CoNLLU, "conllu", false, "input is in CoNLL-U format, e.g. output of word.tokenizer -format conllu, and is not parsed")
+	dependenciesCommand.DurationVar(&UDPIPE_OPTIONS.Config.LoadTimeout, "udpipe-load-timeout", udpipe.DefaultLoadTimeout, "maximum time udpipe may spend loading the model, it is killed once it is exceeded")
+	dependenciesCommand.StringVar(&UDPIPE_OPTIONS.Config.Binary, "udpipe-bin", "", "path of udpipe executable, ./udpipe/udpipe_<os>_<arch> by default")
+	dependenciesCommand.StringVar(&UDPIPE_OPTIONS.Config.Model, "udpipe-model", "", "path of udpipe model, "+udpipe.DefaultModel+" by default")
+	dependenciesCommand.IntVar(&UDPIPE_OPTIONS.Threads, "t", 1, "number of udpipe processes")
+	dependenciesCommand.StringVar(&UDPIPE_OPTIONS.Reject, "reject", "", "file to write lines udpipe failed to parse, they are only logged by default")
+	dependenciesCommand.DurationVar(&UDPIPE_OPTIONS.Timeout, "timeout", 0, "maximum time udpipe may spend on a line, e.g. 30s, the line is rejected and udpipe restarted once it is exceeded")
+
+	bpeTrainCommand := flag.NewFlagSet(bpeTrain, flag.ExitOnError)
+	bpeTrainCommand.IntVar(&VOCAB_SIZE, "vocab", 32000, "target vocabulary size, chars and merged symbols")
+	bpeTrainCommand.IntVar(&MIN_COUNT, "min-count", 2, "minimum frequency of merged pair")
+
+	bpeEncodeCommand := flag.NewFlagSet(bpeEncode, flag.ExitOnError)
+	bpeEncodeCommand.StringVar(&CODES, "codes", "", "file of merges output by bpe.train")
+	bpeEncodeCommand.IntVar(&THREADS, "t", 1, "number of parallel encoders")
+
+	bpeDecodeCommand := flag.NewFlagSet(bpeDecode, flag.ExitOnError)
+
+	vocabCommand := flag.NewFlagSet(vocabulary, flag.ExitOnError)
+	vocabCommand.IntVar(&VOCAB.N, "n", 1, "maximum n-gram length, 1 counts single tokens")
+	vocabCommand.IntVar(&VOCAB.MinCount, "min-count", 1, "minimum count of output n-grams")
+	vocabCommand.IntVar(&VOCAB.Top, "top", 0, "output only this number of the most frequent n-grams, all by default")
+	vocabCommand.IntVar(&VOCAB.MaxEntries, "max-entries", 0, "maximum number of distinct n-grams counted in memory, the rest are spilled to disk, no limit by default")
+	vocabCommand.StringVar(&VOCAB.Dir, "tmp", "", "directory of spill files, system temporary directory by default")
+	vocabCommand.BoolVar(&VOCAB.Tokenize, "tokenize", false, "split input with native word tokenizer, by default input tokens are separated by spaces")
 
 	uniqueCommand := flag.NewFlagSet(unique, flag.ExitOnError)
 	uniqueCommand.BoolVar(&DEBUG, "debug", false, "do nothing only print use cases")
 
 	filterLanguageCommand := flag.NewFlagSet(filterLanguage, flag.ExitOnError)
-	filterLanguageCommand.Var(&languages, "lang", "set of accepted languages")
+	filterLanguageCommand.Var(&languages, "lang", "set of accepted languages as codes, tags or english names, see list.languages")
+	filterLanguageCommand.Var(&excludeLanguages, "exclude", "set of rejected languages, all others are accepted")
 	filterLanguageCommand.BoolVar(&DEBUG, "debug", false, "do othing only print use cases")
+	filterLanguageCommand.StringVar(&LANGUAGE_FILTER.Options.Hints.TLD, "tld", "", "top level domain of the source, e.g. ru, boosts its languages")
+	filterLanguageCommand.StringVar(&LANGUAGE_FILTER.Options.Hints.ContentLanguage, "content-lang", "", "HTTP Content-Language of the source, e.g. ru,en")
+	filterLanguageCommand.StringVar(&LANGUAGE_FILTER.Options.Hints.Language, "expect", "", "expected language as code, tag or english name, e.g. ru")
+	filterLanguageCommand.BoolVar(&LANGUAGE_FILTER.Options.HTML, "html", false, "input is raw html, skip tags and expand entities before detection")
+	filterLanguageCommand.StringVar(&LANGUAGE_FILTER.Annotate, "annotate", "", "output every line annotated with language, reliability, percent and score as tsv or jsonl")
+	filterLanguageCommand.StringVar(&LANGUAGE_FILTER.Reject, "reject", "", "file to write lines not accepted by the filter or failed detection")
+	filterLanguageCommand.IntVar(&LANGUAGE_FILTER.Threads, "t", 1, "number of threads for parallel language detection")
+	filterLanguageCommand.BoolVar(&LANGUAGE_FILTER.Unordered, "unordered", false, "output lines as soon as detected, not preserving input order")
+	filterLanguageCommand.IntVar(&LANGUAGE_FILTER.MinPercent, "min-percent", 0, "minimum percent of text in detected language")
+	filterLanguageCommand.Float64Var(&LANGUAGE_FILTER.MinScore, "min-score", 0, "minimum normalized score of detected language")
+	filterLanguageCommand.BoolVar(&LANGUAGE_FILTER.ReliableOnly, "reliable-only", false, "accept only lines reliably detected")
+	filterLanguageCommand.IntVar(&LANGUAGE_FILTER.MinBytes, "min-bytes", 0, "minimum number of letter bytes scored by detector")
+	filterLanguageCommand.BoolVar(&LANGUAGE_FILTER.Document, "doc", false, "detect language of whole documents delimited by separator lines")
+	filterLanguageCommand.StringVar(&LANGUAGE_FILTER.Separator, "separator", "", "line delimiting documents, blank line by default")
+
+	splitLanguageCommand := flag.NewFlagSet(splitLanguage, flag.ExitOnError)
+	splitLanguageCommand.Var(&languages, "lang", "set of accepted languages, if empty all pieces are output prefixed with language code")
+	splitLanguageCommand.IntVar(&MIN_PIECE_LEN, "min", 1, "minimun piece length in chars")
+
+	splitByLanguageCommand := flag.NewFlagSet(splitByLanguage, flag.ExitOnError)
+	splitByLanguageCommand.StringVar(&OUTPUT, "o", ".", "output directory for <lang>.txt files")
+	splitByLanguageCommand.BoolVar(&GZIP, "z", false, "gzip output files")
+
+	listLanguagesCommand := flag.NewFlagSet(listLanguages, flag.ExitOnError)
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
@@ -98,6 +219,9 @@ func main() {
 		fmt.Fprintf(os.Stderr, "%s\n", normalizeHtmlEntities)
 		normalizeHtmlEntitiesCommand.PrintDefaults()
 
+		fmt.Fprintf(os.Stderr, "%s\n", normalizeText)
+		normalizeTextCommand.PrintDefaults()
+
 		fmt.Fprintf(os.Stderr, "%s\n", stripHtml)
 		stripHtmlCommand.PrintDefaults()
 
@@ -107,9 +231,33 @@ func main() {
 		fmt.Fprintf(os.Stderr, "%s\n", sentences)
 		sentenceCommand.PrintDefaults()
 
+		fmt.Fprintf(os.Stderr, "%s\n", extractDependencies)
+		dependenciesCommand.PrintDefaults()
+
 		fmt.Fprintf(os.Stderr, "%s\n", filterLanguage)
 		filterLanguageCommand.PrintDefaults()
 
+		fmt.Fprintf(os.Stderr, "%s\n", splitLanguage)
+		splitLanguageCommand.PrintDefaults()
+
+		fmt.Fprintf(os.Stderr, "%s\n", splitByLanguage)
+		splitByLanguageCommand.PrintDefaults()
+
+		fmt.Fprintf(os.Stderr, "%s\n", listLanguages)
+		listLanguagesCommand.PrintDefaults()
+
+		fmt.Fprintf(os.Stderr, "%s\n", bpeTrain)
+		bpeTrainCommand.PrintDefaults()
+
+		fmt.Fprintf(os.Stderr, "%s\n", bpeEncode)
+		bpeEncodeCommand.PrintDefaults()
+
+		fmt.Fprintf(os.Stderr, "%s\n", bpeDecode)
+		bpeDecodeCommand.PrintDefaults()
+
+		fmt.Fprintf(os.Stderr, "%s\n", vocabulary)
+		vocabCommand.PrintDefaults()
+
 		fmt.Fprintf(os.Stderr, "%s\n", unique)
 		uniqueCommand.PrintDefaults()
 
@@ -133,6 +281,9 @@ func main() {
 	case normalizeHtmlEntities:
 		normalizeHtmlEntitiesCommand.Parse(os.Args[2:])
 
+	case normalizeText:
+		normalizeTextCommand.Parse(os.Args[2:])
+
 	case stripHtml:
 		stripHtmlCommand.Parse(os.Args[2:])
 
@@ -142,9 +293,33 @@ func main() {
 	case sentences:
 		sentenceCommand.Parse(os.Args[2:])
 
+	case extractDependencies:
+		dependenciesCommand.Parse(os.Args[2:])
+
 	case filterLanguage:
 		filterLanguageCommand.Parse(os.Args[2:])
 
+	case splitLanguage:
+		splitLanguageCommand.Parse(os.Args[2:])
+
+	case splitByLanguage:
+		splitByLanguageCommand.Parse(os.Args[2:])
+
+	case listLanguages:
+		listLanguagesCommand.Parse(os.Args[2:])
+
+	case bpeTrain:
+		bpeTrainCommand.Parse(os.Args[2:])
+
+	case bpeEncode:
+		bpeEncodeCommand.Parse(os.Args[2:])
+
+	case bpeDecode:
+		bpeDecodeCommand.Parse(os.Args[2:])
+
+	case vocabulary:
+		vocabCommand.Parse(os.Args[2:])
+
 	case unique:
 		uniqueCommand.Parse(os.Args[2:])
 
@@ -171,6 +346,14 @@ func main() {
 		gorpora.NormalizeHtmlEntities()
 		return
 	}
+	// NORMALIZE TEXT COMMAND ISSUED
+	if normalizeTextCommand.Parsed() {
+		if e := gorpora.NormalizeText(&NORMALIZATION); e != nil {
+			log.Fatal(e)
+		}
+		return
+	}
+
 	// STRIP HTML ENTITIES COMMAND ISSUED
 	if stripHtmlCommand.Parsed() {
 		gorpora.StripHtml()
@@ -179,13 +362,73 @@ func main() {
 
 	// SPLIT COMMAND ISSUED
 	if tokenizeCommand.Parsed() {
-		gorpora.Split(UDPIPE, LEMMAS)
+		TOKEN_FILTER.KeepPos = keepPos
+		TOKEN_FILTER.DropPos = dropPos
+		TOKEN_FILTER.Features = features
+		if UDPIPE {
+			ENGINE = gorpora.EngineUdpipe
+		}
+		words := tokenizer.NewWordTokenizer()
+		for _, pattern := range protectedPatterns {
+			re, e := regexp.Compile(pattern)
+			if e != nil {
+				log.Fatal(e)
+			}
+			words.Protected = append(words.Protected, re)
+		}
+		if e := gorpora.Split(ENGINE, LEMMAS, FORMAT, &TOKEN_FILTER, words, &UDPIPE_OPTIONS); e != nil {
+			log.Fatal(e)
+		}
 		return
 	}
 
 	// SENTENCE COMMAND ISSUED
 	if sentenceCommand.Parsed() {
-		gorpora.Sentesize(MIN_LEN, MAX_LEN)
+		if e := gorpora.Sentesize(SENTENCE_ENGINE, SENTENCE_LANGUAGE, MIN_LEN, MAX_LEN, &UDPIPE_OPTIONS); e != nil {
+			log.Fatal(e)
+		}
+		return
+	}
+
+	// EXTRACT DEPENDENCIES COMMAND ISSUED
+	if dependenciesCommand.Parsed() {
+		DEPENDENCIES.Relations = relations
+		DEPENDENCIES.Exclude = excludeRelations
+		if e := gorpora.ExtractDependencies(&DEPENDENCIES, &UDPIPE_OPTIONS); e != nil {
+			log.Fatal(e)
+		}
+		return
+	}
+
+	// BPE TRAIN COMMAND ISSUED
+	if bpeTrainCommand.Parsed() {
+		if e := gorpora.TrainBPE(VOCAB_SIZE, MIN_COUNT); e != nil {
+			log.Fatal(e)
+		}
+		return
+	}
+
+	// BPE ENCODE COMMAND ISSUED
+	if bpeEncodeCommand.Parsed() {
+		if e := gorpora.EncodeBPE(CODES, THREADS); e != nil {
+			log.Fatal(e)
+		}
+		return
+	}
+
+	// BPE DECODE COMMAND ISSUED
+	if bpeDecodeCommand.Parsed() {
+		if e := gorpora.DecodeBPE(); e != nil {
+			log.Fatal(e)
+		}
+		return
+	}
+
+	// VOCAB COMMAND ISSUED
+	if vocabCommand.Parsed() {
+		if e := gorpora.Vocab(&VOCAB); e != nil {
+			log.Fatal(e)
+		}
 		return
 	}
 
@@ -197,11 +440,38 @@ func main() {
 
 	// FILTER LANGUAGES COMMAND ISSUED
 	if filterLanguageCommand.Parsed() {
-		if len(languages) == 0 {
-			log.Printf("no -lang parameters given\n")
+		if len(languages) == 0 && len(excludeLanguages) == 0 && LANGUAGE_FILTER.Annotate == "" {
+			log.Printf("no -lang or -exclude parameters given\n")
 			flag.Usage()
+			os.Exit(1)
+		}
+		LANGUAGE_FILTER.Languages = languages
+		LANGUAGE_FILTER.Exclude = excludeLanguages
+		if e := gorpora.FilterLanguage(&LANGUAGE_FILTER); e != nil {
+			log.Fatal(e)
+		}
+		return
+	}
+
+	// SPLIT LANGUAGES COMMAND ISSUED
+	if splitLanguageCommand.Parsed() {
+		if e := gorpora.SplitLanguage(languages, MIN_PIECE_LEN); e != nil {
+			log.Fatal(e)
+		}
+		return
+	}
+
+	// LIST LANGUAGES COMMAND ISSUED
+	if listLanguagesCommand.Parsed() {
+		gorpora.ListLanguages()
+		return
+	}
+
+	// SPLIT BY LANGUAGE COMMAND ISSUED
+	if splitByLanguageCommand.Parsed() {
+		if e := gorpora.SplitByLanguage(OUTPUT, GZIP); e != nil {
+			log.Fatal(e)
 		}
-		gorpora.FilterLanguage(languages)
 		return
 	}
 }
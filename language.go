@@ -0,0 +1,411 @@
+package gorpora
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/vseledkin/gorpora/cld2"
+)
+
+// annotation formats of FilterLanguage
+const (
+	AnnotateTSV   = "tsv"
+	AnnotateJSONL = "jsonl"
+)
+
+// LanguageFilter configures FilterLanguage.
+type LanguageFilter struct {
+	// Languages are accepted languages as codes, tags or names.
+	Languages []string
+	// Exclude are rejected languages, all other languages are accepted
+	// unless Languages are given too.
+	Exclude []string
+	// Options are forwarded to CLD2, hints resolve short lines toward
+	// the known prior, HTML lets CLD2 skip markup of raw web pages.
+	Options cld2.Options
+	// Annotate when set to AnnotateTSV or AnnotateJSONL makes every line
+	// output with its detected language, reliability, percent and score.
+	Annotate string
+	// Reject is a path of the file receiving lines not accepted.
+	Reject string
+	// Threads is the number of parallel detectors.
+	Threads int
+	// Unordered lets lines be output as soon as they are detected
+	// instead of in input order.
+	Unordered bool
+	// MinPercent is the minimum share of text in the detected language.
+	MinPercent int
+	// MinScore is the minimum normalized score of the detected language.
+	MinScore float64
+	// ReliableOnly rejects lines CLD2 marks as unreliable.
+	ReliableOnly bool
+	// MinBytes is the minimum number of letter bytes CLD2 scored.
+	MinBytes int
+	// Document makes language detected over whole documents made of
+	// lines up to a Separator line, documents are kept or dropped together.
+	Document bool
+	// Separator is the line delimiting documents, empty means blank lines.
+	Separator string
+}
+
+// confident reports if the detection result passes the filter thresholds.
+func (f *LanguageFilter) confident(result cld2.Result) bool {
+	if f.ReliableOnly && !result.Reliable {
+		return false
+	}
+	if result.TextBytes < f.MinBytes {
+		return false
+	}
+	top := result.Top()
+	return top.Percent >= f.MinPercent && top.Score >= f.MinScore
+}
+
+type detection struct {
+	result cld2.Result
+	err    error
+}
+
+type languageAnnotation struct {
+	Text     string  `json:"text"`
+	Language string  `json:"lang"`
+	Reliable bool    `json:"reliable"`
+	Percent  int     `json:"percent"`
+	Score    float64 `json:"score"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// writeAnnotation writes text with its detection result, text CLD2
+// failed on is written as unknown language with the error in JSONL.
+func writeAnnotation(w io.Writer, format, text string, result cld2.Result, err error) error {
+	if err != nil {
+		result = cld2.Result{Code: "un"}
+	}
+	top := result.Top()
+	switch format {
+	case AnnotateTSV:
+		_, e := fmt.Fprintf(w, "%s\t%t\t%d\t%s\t%s\n", result.Code, result.Reliable, top.Percent, strconv.FormatFloat(top.Score, 'f', -1, 64), text)
+		return e
+	case AnnotateJSONL:
+		annotation := &languageAnnotation{
+			Text:     text,
+			Language: result.Code,
+			Reliable: result.Reliable,
+			Percent:  top.Percent,
+			Score:    top.Score,
+		}
+		if err != nil {
+			annotation.Error = err.Error()
+		}
+		bits, e := json.Marshal(annotation)
+		if e != nil {
+			return e
+		}
+		_, e = w.Write(append(bits, '\n'))
+		return e
+	}
+	return fmt.Errorf("unknown annotation format %q", format)
+}
+
+// FilterLanguage outputs lines detected as one of the accepted languages,
+// or every line annotated with detection results in annotate mode.
+func FilterLanguage(filter *LanguageFilter) error {
+	if filter.Annotate != "" && filter.Annotate != AnnotateTSV && filter.Annotate != AnnotateJSONL {
+		return fmt.Errorf("unknown annotation format %q", filter.Annotate)
+	}
+	accepted, e := languageSet(filter.Languages)
+	if e != nil {
+		return e
+	}
+	excluded, e := languageSet(filter.Exclude)
+	if e != nil {
+		return e
+	}
+	options := filter.Options
+	if options.Hints.Language != "" {
+		if options.Hints.Language = cld2.LanguageCode(filter.Options.Hints.Language); options.Hints.Language == "" {
+			return fmt.Errorf("unknown expected language %q, see list.languages", filter.Options.Hints.Language)
+		}
+	}
+	var reject *bufio.Writer
+	if filter.Reject != "" {
+		f, e := os.Create(filter.Reject)
+		if e != nil {
+			return e
+		}
+		defer f.Close()
+		reject = bufio.NewWriter(f)
+		defer reject.Flush()
+	}
+
+	var separator string
+	if filter.Document {
+		separator = filter.Separator + "\n"
+	}
+	detect := func(line string) interface{} {
+		result, err := cld2.DetectWithOptions(line, options)
+		return &detection{result, err}
+	}
+	emit := func(line string, r interface{}) error {
+		d := r.(*detection)
+		if d.err != nil {
+			log.Println(d.err)
+		}
+		accept := d.err == nil && (len(accepted) == 0 || accepted[d.result.Code]) && !excluded[d.result.Code] && filter.confident(d.result)
+		if !accept && reject != nil {
+			if _, e := reject.WriteString(line + separator); e != nil {
+				return e
+			}
+		}
+		if filter.Annotate != "" {
+			text := strings.TrimRight(line, "\r\n")
+			if filter.Document && filter.Annotate == AnnotateTSV {
+				text = strings.Replace(text, "\n", "\\n", -1)
+			}
+			return writeAnnotation(os.Stdout, filter.Annotate, text, d.result, d.err)
+		}
+		if accept {
+			_, e := os.Stdout.WriteString(line + separator)
+			return e
+		}
+		return nil
+	}
+	if filter.Document {
+		return parallelRecords(documentReader(os.Stdin, filter.Separator), filter.Threads, filter.Unordered, detect, emit)
+	}
+	return parallelLines(os.Stdin, filter.Threads, filter.Unordered, detect, emit)
+}
+
+// documentReader returns a function reading documents made of lines up
+// to a separator line, an empty separator matches blank lines. Returned
+// documents keep their line endings but not the separator line, empty
+// documents are skipped.
+func documentReader(r io.Reader, separator string) func() (string, error) {
+	reader := bufio.NewReader(r)
+	return func() (string, error) {
+		var document strings.Builder
+		for {
+			line, err := reader.ReadString('\n')
+			if len(line) > 0 && err == io.EOF {
+				line += "\n"
+			}
+			if len(line) > 0 {
+				if strings.TrimRight(line, "\r\n") == separator || separator == "" && len(strings.TrimSpace(line)) == 0 {
+					if document.Len() > 0 {
+						return document.String(), nil
+					}
+				} else {
+					document.WriteString(line)
+				}
+			}
+			if err != nil {
+				if document.Len() > 0 {
+					return document.String(), nil
+				}
+				return "", err
+			}
+		}
+	}
+}
+
+// languageSet maps language codes, tags or names to a set of CLD2 codes.
+func languageSet(names []string) (map[string]bool, error) {
+	set := make(map[string]bool)
+	for _, name := range names {
+		code := cld2.LanguageCode(name)
+		if code == "" {
+			return nil, fmt.Errorf("unknown language %q, see list.languages", name)
+		}
+		set[code] = true
+	}
+	return set, nil
+}
+
+// ListLanguages outputs code and name of every language CLD2 knows.
+func ListLanguages() {
+	for _, language := range cld2.Languages() {
+		os.Stdout.WriteString(language.Code + "\t" + language.Name + "\n")
+	}
+}
+
+// mergeChunks joins adjacent chunks detected as the same language.
+func mergeChunks(chunks []cld2.Chunk) []cld2.Chunk {
+	var merged []cld2.Chunk
+	for _, chunk := range chunks {
+		if last := len(merged) - 1; last >= 0 && merged[last].Code == chunk.Code {
+			merged[last].Length += chunk.Length
+			continue
+		}
+		merged = append(merged, chunk)
+	}
+	return merged
+}
+
+// SplitLanguage splits mixed language lines into per language pieces.
+// If languages are given only pieces in these languages are written
+// one per line, otherwise every piece is written as "code\tpiece".
+// Pieces shorter than min utf8 chars are dropped.
+func SplitLanguage(languages []string, min int) error {
+	accepted, e := languageSet(languages)
+	if e != nil {
+		return e
+	}
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		chunks, err := cld2.Segment(line)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		for _, chunk := range mergeChunks(chunks) {
+			piece := strings.TrimSpace(chunk.Text(line))
+			if utf8.RuneCountInString(piece) < min {
+				continue
+			}
+			if len(accepted) > 0 {
+				if accepted[chunk.Code] {
+					os.Stdout.WriteString(piece)
+					os.Stdout.WriteString("\n")
+				}
+				continue
+			}
+			os.Stdout.WriteString(chunk.Code)
+			os.Stdout.WriteString("\t")
+			os.Stdout.WriteString(piece)
+			os.Stdout.WriteString("\n")
+		}
+	}
+	return nil
+}
+
+// unknownLanguage is the shard name for lines CLD2 could not detect
+const unknownLanguage = "unknown"
+
+type languageShard struct {
+	file   *os.File
+	gz     *gzip.Writer
+	writer *bufio.Writer
+	count  int
+}
+
+func (s *languageShard) Close() error {
+	if e := s.writer.Flush(); e != nil {
+		s.file.Close()
+		return e
+	}
+	if s.gz != nil {
+		if e := s.gz.Close(); e != nil {
+			s.file.Close()
+			return e
+		}
+	}
+	return s.file.Close()
+}
+
+// SplitByLanguage writes every line to <outDir>/<lang>.txt, or .txt.gz
+// if compress is set, lines CLD2 could not detect go to the unknown
+// shard. Per language line counts are logged at the end.
+func SplitByLanguage(outDir string, compress bool) (e error) {
+	if e = os.MkdirAll(outDir, os.ModePerm); e != nil {
+		return e
+	}
+	shards := make(map[string]*languageShard)
+	defer func() {
+		for _, shard := range shards {
+			if ce := shard.Close(); ce != nil && e == nil {
+				e = ce
+			}
+		}
+		logShardCounts(shards)
+	}()
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			if err == io.EOF {
+				line += "\n"
+			}
+			result, de := cld2.DetectFull(line)
+			if de != nil {
+				log.Println(de)
+			}
+			language := result.Code
+			if language == "" || language == "un" || language == "xxx" {
+				language = unknownLanguage
+			}
+			shard, ok := shards[language]
+			if !ok {
+				if shard, e = openShard(outDir, language, compress); e != nil {
+					return e
+				}
+				shards[language] = shard
+			}
+			if _, e = shard.writer.WriteString(line); e != nil {
+				return e
+			}
+			shard.count++
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func openShard(outDir, language string, compress bool) (*languageShard, error) {
+	fsPath := path.Join(outDir, language+".txt")
+	if compress {
+		fsPath += ".gz"
+	}
+	f, e := os.Create(fsPath)
+	if e != nil {
+		return nil, e
+	}
+	shard := &languageShard{file: f}
+	if compress {
+		shard.gz = gzip.NewWriter(f)
+		shard.writer = bufio.NewWriter(shard.gz)
+	} else {
+		shard.writer = bufio.NewWriter(f)
+	}
+	return shard, nil
+}
+
+func logShardCounts(shards map[string]*languageShard) {
+	languages := make([]string, 0, len(shards))
+	total := 0
+	for language, shard := range shards {
+		languages = append(languages, language)
+		total += shard.count
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		if shards[languages[i]].count == shards[languages[j]].count {
+			return languages[i] < languages[j]
+		}
+		return shards[languages[i]].count > shards[languages[j]].count
+	})
+	for _, language := range languages {
+		log.Printf("%s\t%d\n", language, shards[language].count)
+	}
+	log.Println(total, "lines total")
+}
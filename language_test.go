@@ -0,0 +1,221 @@
+package gorpora
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vseledkin/gorpora/cld2"
+)
+
+func readDocuments(input, separator string) []string {
+	read := documentReader(strings.NewReader(input), separator)
+	var documents []string
+	for {
+		document, err := read()
+		if err != nil {
+			return documents
+		}
+		documents = append(documents, document)
+	}
+}
+
+func TestDocumentReader(t *testing.T) {
+	got := readDocuments("a\nb\n\n\n \nc\n\nd", "")
+	expected := []string{"a\nb\n", "c\n", "d\n"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %q got %q", expected, got)
+	}
+	got = readDocuments("a\n\nb\n---\n---\nc\n---\n", "---")
+	expected = []string{"a\n\nb\n", "c\n"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %q got %q", expected, got)
+	}
+}
+
+func TestWriteAnnotation(t *testing.T) {
+	result := cld2.Result{
+		Code:      "ru",
+		Languages: [3]cld2.Language{{Code: "en", Percent: 30, Score: 0.5}, {Code: "ru", Percent: 70, Score: 1.25}},
+		Reliable:  true,
+	}
+	for _, test := range []struct {
+		format   string
+		result   cld2.Result
+		err      error
+		expected string
+	}{
+		{AnnotateTSV, result, nil, "ru\ttrue\t70\t1.25\tкот\n"},
+		{AnnotateJSONL, result, nil, `{"text":"кот","lang":"ru","reliable":true,"percent":70,"score":1.25}` + "\n"},
+		{AnnotateTSV, result, cld2.ErrInvalidUTF8, "un\tfalse\t0\t0\tкот\n"},
+		{AnnotateJSONL, result, cld2.ErrInvalidUTF8, `{"text":"кот","lang":"un","reliable":false,"percent":0,"score":0,"error":"cld2: text is not valid utf8"}` + "\n"},
+	} {
+		var b bytes.Buffer
+		if e := writeAnnotation(&b, test.format, "кот", test.result, test.err); e != nil {
+			t.Fatal(e)
+		}
+		if b.String() != test.expected {
+			t.Errorf("%s: expected %q got %q", test.format, test.expected, b.String())
+		}
+	}
+	if e := writeAnnotation(&bytes.Buffer{}, "xml", "кот", result, nil); e == nil {
+		t.Error("expected error on unknown format")
+	}
+}
+
+func TestConfident(t *testing.T) {
+	result := cld2.Result{
+		Code:      "ru",
+		Languages: [3]cld2.Language{{Code: "en", Percent: 30, Score: 0.5}, {Code: "ru", Percent: 70, Score: 1.25}},
+		TextBytes: 40,
+		Reliable:  true,
+	}
+	unreliable := result
+	unreliable.Reliable = false
+	for _, test := range []struct {
+		filter    LanguageFilter
+		result    cld2.Result
+		confident bool
+	}{
+		{LanguageFilter{}, result, true},
+		{LanguageFilter{}, unreliable, true},
+		{LanguageFilter{ReliableOnly: true}, unreliable, false},
+		{LanguageFilter{ReliableOnly: true}, result, true},
+		{LanguageFilter{MinPercent: 70}, result, true},
+		{LanguageFilter{MinPercent: 71}, result, false},
+		{LanguageFilter{MinScore: 1.25}, result, true},
+		{LanguageFilter{MinScore: 1.5}, result, false},
+		{LanguageFilter{MinBytes: 40}, result, true},
+		{LanguageFilter{MinBytes: 41}, result, false},
+		{LanguageFilter{MinPercent: 50, MinScore: 1, MinBytes: 10, ReliableOnly: true}, result, true},
+		{LanguageFilter{MinPercent: 50, MinScore: 1, MinBytes: 10, ReliableOnly: true}, unreliable, false},
+	} {
+		if confident := test.filter.confident(test.result); confident != test.confident {
+			t.Errorf("%+v: expected confident %t got %t", test.filter, test.confident, confident)
+		}
+	}
+}
+
+func TestMergeChunks(t *testing.T) {
+	for _, test := range []struct {
+		chunks   []cld2.Chunk
+		expected []cld2.Chunk
+	}{
+		{nil, nil},
+		{[]cld2.Chunk{{Offset: 0, Length: 5, Code: "ru"}}, []cld2.Chunk{{Offset: 0, Length: 5, Code: "ru"}}},
+		{
+			[]cld2.Chunk{{Offset: 0, Length: 5, Code: "ru"}, {Offset: 5, Length: 3, Code: "ru"}, {Offset: 8, Length: 4, Code: "en"}, {Offset: 12, Length: 2, Code: "ru"}},
+			[]cld2.Chunk{{Offset: 0, Length: 8, Code: "ru"}, {Offset: 8, Length: 4, Code: "en"}, {Offset: 12, Length: 2, Code: "ru"}},
+		},
+		{
+			[]cld2.Chunk{{Offset: 0, Length: 1, Code: "un"}, {Offset: 1, Length: 1, Code: "un"}, {Offset: 2, Length: 1, Code: "un"}},
+			[]cld2.Chunk{{Offset: 0, Length: 3, Code: "un"}},
+		},
+	} {
+		if merged := mergeChunks(test.chunks); !reflect.DeepEqual(merged, test.expected) {
+			t.Errorf("expected %v got %v", test.expected, merged)
+		}
+	}
+}
+
+func TestOpenShard(t *testing.T) {
+	dir := t.TempDir()
+	for _, compress := range []bool{false, true} {
+		shard, e := openShard(dir, "ru", compress)
+		if e != nil {
+			t.Fatal(e)
+		}
+		shard.writer.WriteString("кот\nпёс\n")
+		if e = shard.Close(); e != nil {
+			t.Fatal(e)
+		}
+		name := path.Join(dir, "ru.txt")
+		if compress {
+			name += ".gz"
+		}
+		f, e := os.Open(name)
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer f.Close()
+		var r io.Reader = f
+		if compress {
+			if r, e = gzip.NewReader(f); e != nil {
+				t.Fatal(e)
+			}
+		}
+		bits, e := ioutil.ReadAll(r)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if string(bits) != "кот\nпёс\n" {
+			t.Errorf("compress %t: unexpected shard content %q", compress, bits)
+		}
+	}
+}
+
+func TestSplitByLanguage(t *testing.T) {
+	input := path.Join(t.TempDir(), "input.txt")
+	lines := "Мама мыла раму и пела песню.\nThe quick brown fox jumps over the lazy dog.\nbad \xff line\nno newline"
+	if e := ioutil.WriteFile(input, []byte(lines), 0644); e != nil {
+		t.Fatal(e)
+	}
+	stdin, e := os.Open(input)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer func(f *os.File) { os.Stdin = f }(os.Stdin)
+	os.Stdin = stdin
+	defer stdin.Close()
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+	dir := t.TempDir()
+	if e = SplitByLanguage(dir, false); e != nil {
+		t.Fatal(e)
+	}
+	shards, e := ioutil.ReadDir(dir)
+	if e != nil {
+		t.Fatal(e)
+	}
+	var written []string
+	for _, shard := range shards {
+		bits, e := ioutil.ReadFile(path.Join(dir, shard.Name()))
+		if e != nil {
+			t.Fatal(e)
+		}
+		written = append(written, strings.SplitAfter(strings.TrimSuffix(string(bits), "\n"), "\n")...)
+	}
+	if len(written) != 4 {
+		t.Errorf("expected every line in a shard got %q", written)
+	}
+	if bits, e := ioutil.ReadFile(path.Join(dir, unknownLanguage+".txt")); e != nil || !strings.Contains(string(bits), "bad \xff line\n") {
+		t.Errorf("expected invalid utf8 line in unknown shard got %q %v", bits, e)
+	}
+}
+
+func TestLogShardCounts(t *testing.T) {
+	var b bytes.Buffer
+	log.SetOutput(&b)
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	}()
+	logShardCounts(map[string]*languageShard{
+		"en":            {count: 2},
+		"ru":            {count: 5},
+		"de":            {count: 2},
+		unknownLanguage: {count: 1},
+	})
+	expected := "ru\t5\nde\t2\nen\t2\nunknown\t1\n10 lines total\n"
+	if b.String() != expected {
+		t.Errorf("expected %q got %q", expected, b.String())
+	}
+}
@@ -0,0 +1,127 @@
+package gorpora
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Unicode normalization forms of Normalization.
+const (
+	FormNFC  = "nfc"
+	FormNFKC = "nfkc"
+)
+
+// Normalization selects steps of NormalizeText, they are applied in the
+// order of fields.
+type Normalization struct {
+	// Form is Unicode normalization form, FormNFC or FormNFKC, text is
+	// not normalized if it is empty.
+	Form string
+	// Invisible removes zero-width, format and control characters.
+	Invisible bool
+	// Punctuation unifies quotes to " and ', hyphens and minus to -,
+	// dashes to — and replaces ellipsis char with three dots.
+	Punctuation bool
+	// Lower lowercases text.
+	Lower bool
+	// Yo replaces ё with е.
+	Yo bool
+	// Digits masks every digit with 0.
+	Digits bool
+	// Space replaces runs of spaces with single space and trims text.
+	Space bool
+}
+
+// punctuation maps quotes, hyphens and dashes to their unified forms.
+var punctuation = map[rune]rune{
+	'«': '"', '»': '"', '„': '"', '“': '"', '”': '"', '‟': '"', '″': '"', '＂': '"',
+	'‘': '\'', '’': '\'', '‚': '\'', '‛': '\'', '′': '\'', '`': '\'', '´': '\'', '＇': '\'',
+	'‐': '-', '‑': '-', '−': '-', '﹣': '-', '－': '-',
+	'‒': '—', '–': '—', '―': '—', '⸺': '—', '⸻': '—',
+}
+
+// Normalize returns text with selected normalization steps applied.
+func (n *Normalization) Normalize(text string) string {
+	switch n.Form {
+	case FormNFC:
+		text = norm.NFC.String(text)
+	case FormNFKC:
+		text = norm.NFKC.String(text)
+	}
+	if n.Invisible {
+		text = strings.Map(func(r rune) rune {
+			if unicode.Is(unicode.Cf, r) || unicode.IsControl(r) && !unicode.IsSpace(r) {
+				return -1
+			}
+			return r
+		}, text)
+	}
+	if n.Punctuation {
+		text = strings.Map(func(r rune) rune {
+			if p, ok := punctuation[r]; ok {
+				return p
+			}
+			return r
+		}, text)
+		text = strings.Replace(text, "…", "...", -1)
+	}
+	if n.Lower {
+		text = strings.ToLower(text)
+	}
+	if n.Yo {
+		text = strings.Map(func(r rune) rune {
+			switch r {
+			case 'ё':
+				return 'е'
+			case 'Ё':
+				return 'Е'
+			}
+			return r
+		}, text)
+	}
+	if n.Digits {
+		text = strings.Map(func(r rune) rune {
+			if unicode.IsDigit(r) {
+				return '0'
+			}
+			return r
+		}, text)
+	}
+	if n.Space {
+		text = strings.Join(strings.Fields(text), " ")
+	}
+	return text
+}
+
+// NormalizeText outputs input lines normalized as n selects.
+func NormalizeText(n *Normalization) error {
+	switch n.Form {
+	case "", FormNFC, FormNFKC:
+	default:
+		return fmt.Errorf("unknown normalization form %q, expected %s or %s", n.Form, FormNFC, FormNFKC)
+	}
+	reader := bufio.NewReader(os.Stdin)
+	writer := bufio.NewWriter(os.Stdout)
+	defer writer.Flush()
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			writer.WriteString(n.Normalize(strings.TrimSuffix(line, "\n")))
+			if _, e := writer.WriteString("\n"); e != nil {
+				return e
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package gorpora
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	for _, c := range []struct {
+		normalization Normalization
+		text          string
+		expected      string
+	}{
+		{Normalization{}, "Ёж «ест»", "Ёж «ест»"},
+		{Normalization{Form: FormNFC}, "е\u0308ж", "ёж"},
+		{Normalization{Form: FormNFKC}, "ﬁ ２", "fi 2"},
+		{Normalization{Invisible: true}, "сло\u200bво\u00ad\x07\tконец\ufeff", "слово\tконец"},
+		{Normalization{Punctuation: true}, "«Да» — „нет“ ‘x’ 5−3 и‐так…", `"Да" — "нет" 'x' 5-3 и-так...`},
+		{Normalization{Lower: true, Yo: true}, "Ёлка ЁЖ", "елка еж"},
+		{Normalization{Digits: true}, "в 2019 году ٣", "в 0000 году 0"},
+		{Normalization{Space: true}, "  много \t  пробелов  ", "много пробелов"},
+		{Normalization{Form: FormNFC, Invisible: true, Punctuation: true, Lower: true, Yo: true, Digits: true, Space: true},
+			" Ещ\u0435\u0308\u200b  «15» ёжиков… ", `еще "00" ежиков...`},
+	} {
+		if normalized := c.normalization.Normalize(c.text); normalized != c.expected {
+			t.Errorf("%+v: expected %q got %q", c.normalization, c.expected, normalized)
+		}
+	}
+}
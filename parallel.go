@@ -0,0 +1,107 @@
+package gorpora
+
+import (
+	"bufio"
+	"io"
+	"sync"
+)
+
+// linesInFlight is the number of lines per thread read ahead of output,
+// it bounds memory used to restore input order.
+const linesInFlight = 64
+
+type lineJob struct {
+	n      int
+	line   string
+	result interface{}
+}
+
+// parallelLines reads lines from r and applies work to every line on
+// threads goroutines. Results are passed to emit in input order, or as
+// soon as they are ready if unordered is set. Processing stops at the
+// first error returned by emit.
+func parallelLines(r io.Reader, threads int, unordered bool, work func(line string) interface{}, emit func(line string, result interface{}) error) error {
+	reader := bufio.NewReader(r)
+	read := func() (string, error) {
+		return reader.ReadString('\n')
+	}
+	return parallelRecords(read, threads, unordered, work, emit)
+}
+
+// parallelRecords is like parallelLines but records are taken from
+// read until it returns an error.
+func parallelRecords(read func() (string, error), threads int, unordered bool, work func(record string) interface{}, emit func(record string, result interface{}) error) error {
+	if threads < 1 {
+		threads = 1
+	}
+	licence := make(chan struct{}, threads*linesInFlight)
+	for i := 0; i < cap(licence); i++ {
+		licence <- struct{}{}
+	}
+	jobs := make(chan *lineJob, threads)
+	results := make(chan *lineJob, threads)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		defer close(jobs)
+		for n := 0; ; n++ {
+			line, err := read()
+			if err != nil {
+				return
+			}
+			select {
+			case <-licence:
+			case <-done:
+				return
+			}
+			select {
+			case jobs <- &lineJob{n: n, line: line}:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var workers sync.WaitGroup
+	for i := 0; i < threads; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for job := range jobs {
+				job.result = work(job.line)
+				select {
+				case results <- job:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		workers.Wait()
+		close(results)
+	}()
+
+	pending := make(map[int]*lineJob)
+	next := 0
+	for job := range results {
+		if unordered {
+			if e := emit(job.line, job.result); e != nil {
+				return e
+			}
+			licence <- struct{}{}
+			continue
+		}
+		pending[job.n] = job
+		for job, ok := pending[next]; ok; job, ok = pending[next] {
+			delete(pending, next)
+			next++
+			if e := emit(job.line, job.result); e != nil {
+				return e
+			}
+			licence <- struct{}{}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package gorpora
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParallelLinesOrder(t *testing.T) {
+	var input strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&input, "%d\n", i)
+	}
+	work := func(line string) interface{} {
+		if len(line)%2 == 0 {
+			time.Sleep(time.Microsecond)
+		}
+		return strings.TrimSpace(line)
+	}
+	var output []string
+	emit := func(line string, result interface{}) error {
+		output = append(output, result.(string))
+		return nil
+	}
+	if e := parallelLines(strings.NewReader(input.String()), 8, false, work, emit); e != nil {
+		t.Fatal(e)
+	}
+	if len(output) != 1000 {
+		t.Fatalf("expected 1000 lines got %d", len(output))
+	}
+	for i, line := range output {
+		if line != fmt.Sprint(i) {
+			t.Fatalf("line %d is out of order: %s", i, line)
+		}
+	}
+}
+
+func TestParallelLinesEmitError(t *testing.T) {
+	input := strings.Repeat("line\n", 10000)
+	stop := fmt.Errorf("stop")
+	count := 0
+	emit := func(line string, result interface{}) error {
+		count++
+		if count == 10 {
+			return stop
+		}
+		return nil
+	}
+	work := func(line string) interface{} { return line }
+	if e := parallelLines(strings.NewReader(input), 4, true, work, emit); e != stop {
+		t.Fatalf("expected stop error got %v", e)
+	}
+}
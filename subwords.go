@@ -0,0 +1,74 @@
+package gorpora
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/vseledkin/gorpora/bpe"
+)
+
+// TrainBPE learns BPE merges from tokenized input lines and outputs them.
+func TrainBPE(vocabSize, minCount int) error {
+	counts, e := bpe.Count(os.Stdin)
+	if e != nil {
+		return e
+	}
+	log.Printf("training BPE on %d distinct words\n", len(counts))
+	model := bpe.Train(counts, vocabSize, minCount)
+	log.Printf("learned %d merges\n", len(model.Merges))
+	return model.Write(os.Stdout)
+}
+
+// EncodeBPE splits words of tokenized input lines into subwords with
+// merges read from codes file.
+func EncodeBPE(codes string, threads int) error {
+	if codes == "" {
+		return fmt.Errorf("merges file is not given")
+	}
+	f, e := os.Open(codes)
+	if e != nil {
+		return e
+	}
+	model, e := bpe.Read(f)
+	f.Close()
+	if e != nil {
+		return e
+	}
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	work := func(line string) interface{} {
+		return model.EncodeLine(line)
+	}
+	emit := func(line string, result interface{}) error {
+		out.WriteString(result.(string))
+		_, e := out.WriteString("\n")
+		return e
+	}
+	return parallelLines(os.Stdin, threads, false, work, emit)
+}
+
+// DecodeBPE joins subwords of input lines back into words.
+func DecodeBPE() error {
+	reader := bufio.NewReader(os.Stdin)
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			out.WriteString(bpe.Decode(strings.TrimSuffix(line, "\n")))
+			if _, e := out.WriteString("\n"); e != nil {
+				return e
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,218 @@
+// Package tokenizer splits text into sentences and words with rules
+// written in Go, it needs no external binary or model unlike udpipe.
+package tokenizer
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Span is a piece of text, Start and End are its rune offsets in the
+// text, End is exclusive.
+type Span struct {
+	Start int
+	End   int
+	Text  string
+}
+
+// abbreviations are lower case words per language which never end
+// sentences, like titles before names and words before numbers, so a
+// dot after them is not a sentence end. Inner dots are kept as in "т.е".
+// Abbreviations which may end sentences like "т.д", "руб" or "etc" are
+// not listed, they end sentences when followed by a capital letter.
+var abbreviations = map[string][]string{
+	"ru": {
+		"акад", "англ", "г", "ген", "гл", "гр", "д", "доц", "ед", "жен", "зам", "им",
+		"кв", "лат", "м", "муж", "напр", "нем", "о", "оз", "пер", "пл", "пос", "пр",
+		"проф", "п", "пп", "р", "ред", "рис", "св", "см", "соч", "сост", "ср", "ст",
+		"стр", "т", "т.е", "т.к", "т.н", "т.ч", "табл", "тел", "тов", "тт", "ул",
+		"франц", "ч", "яз",
+	},
+	"en": {
+		"approx", "apr", "aug", "cf", "dec", "dr", "e.g", "feb", "fig", "gen", "i.e",
+		"jan", "jul", "jun", "mr", "mrs", "ms", "mt", "nov", "oct", "p", "pp", "prof",
+		"rep", "sen", "sep", "sept", "st", "vol", "vs",
+	},
+}
+
+// numberAbbreviations are lower case words per language which are
+// abbreviations only when followed by a number as in "No. 5", otherwise
+// they are ordinary words often ending sentences.
+var numberAbbreviations = map[string][]string{
+	"en": {"est", "mar", "no"},
+}
+
+// SentenceSplitter splits text into sentences at dots, question and
+// exclamation marks and ellipses followed by space and a word starting
+// with a capital letter, a digit, a quote or a dialogue dash introducing
+// a new utterance. Initials, abbreviations, decimals and list numbers
+// do not end sentences.
+type SentenceSplitter struct {
+	// Abbreviations are lower case words which are not sentence ends
+	// when followed by a dot.
+	Abbreviations map[string]bool
+	// NumberAbbreviations are lower case words which are not sentence
+	// ends when followed by a dot and a number.
+	NumberAbbreviations map[string]bool
+}
+
+// NewSentenceSplitter returns splitter with abbreviations of language
+// given by ISO 639-1 code, abbreviations of all known languages are used
+// if language is empty.
+func NewSentenceSplitter(language string) (*SentenceSplitter, error) {
+	s := &SentenceSplitter{Abbreviations: make(map[string]bool), NumberAbbreviations: make(map[string]bool)}
+	if language == "" {
+		for language, words := range abbreviations {
+			add(s.Abbreviations, words)
+			add(s.NumberAbbreviations, numberAbbreviations[language])
+		}
+		return s, nil
+	}
+	words, ok := abbreviations[language]
+	if !ok {
+		return nil, fmt.Errorf("no abbreviations for language %q", language)
+	}
+	add(s.Abbreviations, words)
+	add(s.NumberAbbreviations, numberAbbreviations[language])
+	return s, nil
+}
+
+func add(set map[string]bool, words []string) {
+	for _, word := range words {
+		set[word] = true
+	}
+}
+
+// Split returns sentences of text without surrounding space.
+func (s *SentenceSplitter) Split(text string) []Span {
+	runes := []rune(text)
+	var sentences []Span
+	start := skipSpace(runes, 0)
+	for i := start; i < len(runes); i++ {
+		if !isTerminator(runes[i]) {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && isTerminator(runes[end]) {
+			end++
+		}
+		for end < len(runes) && isClosing(runes[end]) {
+			end++
+		}
+		if end == len(runes) {
+			break
+		}
+		if !unicode.IsSpace(runes[end]) && !isWide(runes[end-1]) || !s.boundary(runes, start, i, end) {
+			i = end - 1
+			continue
+		}
+		sentences = append(sentences, newSpan(runes, start, end))
+		start = skipSpace(runes, end)
+		i = start - 1
+	}
+	if end := trimSpace(runes, len(runes)); start < end {
+		sentences = append(sentences, newSpan(runes, start, end))
+	}
+	return sentences
+}
+
+// boundary tells if sentence starting at start ends with terminators
+// starting at dot and followed by space at end.
+func (s *SentenceSplitter) boundary(runes []rune, start, dot, end int) bool {
+	next := skipSpace(runes, end)
+	if runes[dot] == '.' && !isTerminator(runes[dot+1]) {
+		wordStart := dot
+		for wordStart > start && !unicode.IsSpace(runes[wordStart-1]) {
+			wordStart--
+		}
+		for wordStart < dot && isOpening(runes[wordStart]) {
+			wordStart++
+		}
+		word := string(runes[wordStart:dot])
+		last := []rune(word[strings.LastIndex(word, ".")+1:])
+		switch {
+		case len(last) == 1 && unicode.IsUpper(last[0]):
+			return false // initial
+		case s.Abbreviations[strings.ToLower(word)]:
+			return false
+		case s.NumberAbbreviations[strings.ToLower(word)] && next < len(runes) && unicode.IsDigit(runes[next]):
+			return false
+		case wordStart == start && isNumber(word):
+			return false // list item number
+		}
+	}
+	if next < len(runes) && isDash(runes[next]) {
+		// dialogue dash followed by lower case word continues the
+		// utterance with author's words
+		next = skipSpace(runes, next+1)
+	}
+	for next < len(runes) && (isOpening(runes[next]) || isDash(runes[next])) {
+		next++
+	}
+	return next == len(runes) || !unicode.IsLower(runes[next])
+}
+
+func newSpan(runes []rune, start, end int) Span {
+	return Span{Start: start, End: end, Text: string(runes[start:end])}
+}
+
+func skipSpace(runes []rune, i int) int {
+	for i < len(runes) && unicode.IsSpace(runes[i]) {
+		i++
+	}
+	return i
+}
+
+func trimSpace(runes []rune, end int) int {
+	for end > 0 && unicode.IsSpace(runes[end-1]) {
+		end--
+	}
+	return end
+}
+
+func isTerminator(r rune) bool {
+	switch r {
+	case '.', '!', '?', '…', '‼', '⁇', '⁈', '⁉', '。', '！', '？':
+		return true
+	}
+	return false
+}
+
+// isWide tells if terminator ends sentence without following space.
+func isWide(r rune) bool {
+	return r == '。' || r == '！' || r == '？'
+}
+
+func isClosing(r rune) bool {
+	switch r {
+	case '"', '\'', '»', '”', '’', '“', ')', ']', '}':
+		return true
+	}
+	return false
+}
+
+func isOpening(r rune) bool {
+	switch r {
+	case '"', '\'', '«', '„', '“', '‘', '(', '[', '{':
+		return true
+	}
+	return false
+}
+
+func isDash(r rune) bool {
+	switch r {
+	case '-', '–', '—', '―':
+		return true
+	}
+	return false
+}
+
+func isNumber(word string) bool {
+	for _, r := range word {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return len(word) > 0
+}
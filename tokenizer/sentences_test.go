@@ -0,0 +1,49 @@
+package tokenizer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSentenceSplitter(t *testing.T) {
+	splitter, e := NewSentenceSplitter("")
+	if e != nil {
+		t.Fatal(e)
+	}
+	for _, c := range []struct {
+		text      string
+		sentences []string
+	}{
+		{"Мама мыла раму. Папа спал!  Кто там?", []string{"Мама мыла раму.", "Папа спал!", "Кто там?"}},
+		{" Стоимость 3.14 руб. за шт. Дорого. ", []string{"Стоимость 3.14 руб. за шт.", "Дорого."}},
+		{"Живёт на ул. Ленина, д. 5. Работает в г. Москва.", []string{"Живёт на ул. Ленина, д. 5.", "Работает в г. Москва."}},
+		{"Поэт А. С. Пушкин родился в 1799 г. в Москве. Он умер в 1837 г.", []string{"Поэт А. С. Пушкин родился в 1799 г. в Москве.", "Он умер в 1837 г."}},
+		{"Фрукты, овощи и т.д. Всё свежее.", []string{"Фрукты, овощи и т.д.", "Всё свежее."}},
+		{"Всё и т.д. и т.п. Вот так.", []string{"Всё и т.д. и т.п.", "Вот так."}},
+		{"Ну... я не знаю... Может быть.", []string{"Ну... я не знаю...", "Может быть."}},
+		{"Он сказал: «Я приду.» Но не пришёл.", []string{"Он сказал: «Я приду.»", "Но не пришёл."}},
+		{"— Ты придёшь? — спросила она. — Да! — Хорошо.", []string{"— Ты придёшь? — спросила она.", "— Да!", "— Хорошо."}},
+		{"1. Первый пункт. 2. Второй пункт.", []string{"1. Первый пункт.", "2. Второй пункт."}},
+		{"Mr. Smith met Dr. Brown, e.g. at 5 p.m. They talked.", []string{"Mr. Smith met Dr. Brown, e.g. at 5 p.m.", "They talked."}},
+		{"Apples, pears etc. Then Prof. White came.", []string{"Apples, pears etc.", "Then Prof. White came."}},
+		{"He said no. She left. See No. 5 and Vol. 2.", []string{"He said no.", "She left.", "See No. 5 and Vol. 2."}},
+		{"Visit example.com today. It works.", []string{"Visit example.com today.", "It works."}},
+		{"今日は晴れです。明日は雨です。", []string{"今日は晴れです。", "明日は雨です。"}},
+		{"   ", nil},
+	} {
+		var sentences []string
+		runes := []rune(c.text)
+		for _, span := range splitter.Split(c.text) {
+			if string(runes[span.Start:span.End]) != span.Text {
+				t.Errorf("%q: span %d:%d is not %q", c.text, span.Start, span.End, span.Text)
+			}
+			sentences = append(sentences, span.Text)
+		}
+		if !reflect.DeepEqual(sentences, c.sentences) {
+			t.Errorf("%q: expected %q got %q", c.text, c.sentences, sentences)
+		}
+	}
+	if _, e = NewSentenceSplitter("xx"); e == nil {
+		t.Error("expected error for unknown language")
+	}
+}
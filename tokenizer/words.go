@@ -0,0 +1,298 @@
+package tokenizer
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// WordTokenizer splits text into words, numbers, punctuation and symbols.
+// Rules joining word characters and protecting URLs, emails and
+// emoticons are enabled separately.
+type WordTokenizer struct {
+	// Hyphens joins words around hyphens, e.g. что-то or 2-й.
+	Hyphens bool
+	// Apostrophes joins letters around apostrophes, e.g. don't.
+	Apostrophes bool
+	// Numbers joins decimals like 3.14 or 1,5 and digit groups like 1 000.
+	Numbers bool
+	// URLs keeps links starting with scheme or www. as single tokens.
+	URLs bool
+	// Emails keeps email addresses as single tokens.
+	Emails bool
+	// Emoticons keeps emoticons like :-) as single tokens.
+	Emoticons bool
+	// Protected are extra patterns whose matches at token starts are
+	// single tokens, e.g. hashtags. Matching them allocates.
+	Protected []*regexp.Regexp
+}
+
+// NewWordTokenizer returns tokenizer with all rules enabled.
+func NewWordTokenizer() *WordTokenizer {
+	return &WordTokenizer{Hyphens: true, Apostrophes: true, Numbers: true, URLs: true, Emails: true, Emoticons: true}
+}
+
+// Tokenize returns tokens of text.
+func (t *WordTokenizer) Tokenize(text string) []Span {
+	var tokens []Span
+	t.Each(text, func(token string, start, end int) {
+		tokens = append(tokens, Span{Start: start, End: end, Text: token})
+	})
+	return tokens
+}
+
+// Each calls f for every token of text with token text and its rune
+// offsets. Token is a substring of text, so Each does not allocate
+// unless Protected patterns are set.
+func (t *WordTokenizer) Each(text string, f func(token string, start, end int)) {
+	var protected [][]int
+	if len(t.Protected) > 0 {
+		protected = t.protect(text)
+	}
+	pos, rpos := 0, 0
+	for pos < len(text) {
+		r, size := utf8.DecodeRuneInString(text[pos:])
+		if unicode.IsSpace(r) {
+			pos += size
+			rpos++
+			continue
+		}
+		for len(protected) > 0 && protected[0][1] <= pos {
+			protected = protected[1:]
+		}
+		end := 0
+		if len(protected) > 0 && protected[0][0] <= pos {
+			end = protected[0][1]
+		} else {
+			end = t.scan(text, pos)
+			if len(protected) > 0 && protected[0][0] < end {
+				end = protected[0][0]
+			}
+		}
+		n := utf8.RuneCountInString(text[pos:end])
+		f(text[pos:end], rpos, rpos+n)
+		pos, rpos = end, rpos+n
+	}
+}
+
+// protect returns sorted non overlapping byte ranges of Protected matches.
+func (t *WordTokenizer) protect(text string) (ranges [][]int) {
+	for _, pattern := range t.Protected {
+		for _, match := range pattern.FindAllStringIndex(text, -1) {
+			if match[1] > match[0] {
+				ranges = append(ranges, match)
+			}
+		}
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i][0] < ranges[j][0] || ranges[i][0] == ranges[j][0] && ranges[i][1] > ranges[j][1]
+	})
+	merged := ranges[:0]
+	for _, r := range ranges {
+		if len(merged) > 0 && r[0] < merged[len(merged)-1][1] {
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// scan returns byte end of the token starting at pos.
+func (t *WordTokenizer) scan(text string, pos int) int {
+	if t.URLs {
+		if end := scanURL(text, pos); end > pos {
+			return end
+		}
+	}
+	if t.Emails {
+		if end := scanEmail(text, pos); end > pos {
+			return end
+		}
+	}
+	if t.Emoticons {
+		if end := scanEmoticon(text, pos); end > pos {
+			return end
+		}
+	}
+	r, size := utf8.DecodeRuneInString(text[pos:])
+	if isWordRune(r) {
+		return t.scanWord(text, pos)
+	}
+	end := pos + size
+	switch r {
+	case '.', '!', '?':
+		// ellipsis, ?! and !!! are single tokens
+		for end < len(text) && (text[end] == '.' && r == '.' || r != '.' && (text[end] == '!' || text[end] == '?')) {
+			end++
+		}
+	}
+	return end
+}
+
+// scanWord returns byte end of the word starting at pos.
+func (t *WordTokenizer) scanWord(text string, pos int) int {
+	end := pos
+	groups := t.Numbers
+	for end < len(text) {
+		r, size := utf8.DecodeRuneInString(text[end:])
+		if isWordRune(r) {
+			groups = groups && isDigit(r)
+			end += size
+			continue
+		}
+		prev, _ := utf8.DecodeLastRuneInString(text[pos:end])
+		next, _ := utf8.DecodeRuneInString(text[end+size:])
+		switch {
+		case t.Hyphens && isHyphen(r) && isWordRune(next):
+		case t.Apostrophes && isApostrophe(r) && unicode.IsLetter(prev) && unicode.IsLetter(next):
+		case t.Numbers && (r == '.' || r == ',') && isDigit(prev) && isDigit(next):
+			groups = false
+		case groups && isGroupSpace(r) && digitGroup(text, pos, end, end+size):
+		default:
+			return end
+		}
+		end += size
+	}
+	return end
+}
+
+// digitGroup tells if digits from start to end are followed by a group
+// of three digits at next, the first group has at most three digits.
+func digitGroup(text string, start, end, next int) bool {
+	first := end - start
+	if i := strings.IndexFunc(text[start:end], isGroupSpace); i >= 0 {
+		first = i
+	}
+	if first > 3 || next+3 > len(text) {
+		return false
+	}
+	for i := next; i < next+3; i++ {
+		if text[i] < '0' || text[i] > '9' {
+			return false
+		}
+	}
+	after, _ := utf8.DecodeRuneInString(text[next+3:])
+	return !isWordRune(after)
+}
+
+// urlPrefixes start URLs.
+var urlPrefixes = []string{"http://", "https://", "ftp://", "www."}
+
+// scanURL returns byte end of URL starting at pos, pos if there is none.
+func scanURL(text string, pos int) int {
+	if strings.IndexByte("hHfFwW", text[pos]) < 0 {
+		return pos
+	}
+	for _, prefix := range urlPrefixes {
+		if len(text)-pos <= len(prefix) || !strings.EqualFold(text[pos:pos+len(prefix)], prefix) {
+			continue
+		}
+		end := pos + len(prefix)
+		for end < len(text) {
+			r, size := utf8.DecodeRuneInString(text[end:])
+			if unicode.IsSpace(r) {
+				break
+			}
+			end += size
+		}
+		// trailing punctuation belongs to the sentence
+		for end > pos+len(prefix) {
+			r, size := utf8.DecodeLastRuneInString(text[:end])
+			if !strings.ContainsRune(".,;:!?\"'»”)", r) || r == ')' && strings.Contains(text[pos:end], "(") {
+				break
+			}
+			end -= size
+		}
+		if end > pos+len(prefix) {
+			return end
+		}
+	}
+	return pos
+}
+
+// scanEmail returns byte end of email address starting at pos, pos if
+// there is none.
+func scanEmail(text string, pos int) int {
+	end := pos
+	for end < len(text) && (isASCIIAlnum(text[end]) || strings.IndexByte("._%+-", text[end]) >= 0) {
+		end++
+	}
+	if end == pos || !isASCIIAlnum(text[pos]) || end == len(text) || text[end] != '@' {
+		return pos
+	}
+	domain := end + 1
+	end = domain
+	for end < len(text) && (isASCIIAlnum(text[end]) || text[end] == '.' || text[end] == '-') {
+		end++
+	}
+	for end > domain && (text[end-1] == '.' || text[end-1] == '-') {
+		end--
+	}
+	dot := strings.LastIndexByte(text[domain:end], '.')
+	if dot <= 0 || end-(domain+dot+1) < 2 {
+		return pos
+	}
+	return end
+}
+
+// emoticons are sorted so that longer ones go first.
+var emoticons = []string{
+	":-)", ":-(", ":-D", ":-P", ":-p", ";-)", ":'(", "^_^", ")))", "(((",
+	":)", ":(", ":D", ":P", ":p", ";)", ":o", ":O", "=)", "<3", "xD", "XD",
+}
+
+// scanEmoticon returns byte end of emoticon starting at pos, pos if there
+// is none. Repeated mouth like :))) belongs to the emoticon.
+func scanEmoticon(text string, pos int) int {
+	if strings.IndexByte(":;^()=<xX", text[pos]) < 0 {
+		return pos
+	}
+	for _, emoticon := range emoticons {
+		if !strings.HasPrefix(text[pos:], emoticon) {
+			continue
+		}
+		end := pos + len(emoticon)
+		if last := emoticon[len(emoticon)-1]; last == ')' || last == '(' {
+			for end < len(text) && text[end] == last {
+				end++
+			}
+		}
+		if next, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && isWordRune(next) {
+			continue
+		}
+		return end
+	}
+	return pos
+}
+
+func isWordRune(r rune) bool {
+	switch {
+	case r < utf8.RuneSelf:
+		return isASCIIAlnum(byte(r)) || r == '_'
+	case r >= 0x400 && r <= 0x52f && r != 0x482:
+		return true // Cyrillic letters and marks
+	}
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r) || r == '_'
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isASCIIAlnum(b byte) bool {
+	return b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+func isHyphen(r rune) bool {
+	return r == '-' || r == '\u2010'
+}
+
+func isApostrophe(r rune) bool {
+	return r == '\'' || r == '’'
+}
+
+func isGroupSpace(r rune) bool {
+	return r == ' ' || r == '\u00a0' || r == '\u2009' || r == '\u202f'
+}
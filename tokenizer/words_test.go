@@ -0,0 +1,71 @@
+package tokenizer
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestWordTokenizer(t *testing.T) {
+	tokenizer := NewWordTokenizer()
+	for _, c := range []struct {
+		text   string
+		tokens []string
+	}{
+		{"Мама мыла раму.", []string{"Мама", "мыла", "раму", "."}},
+		{"Кто-то сказал: «don't», а потом...", []string{"Кто-то", "сказал", ":", "«", "don't", "»", ",", "а", "потом", "..."}},
+		{"Пи = 3.14, e = 2,71; всего 1 000 000 руб. в 2019 100 раз", []string{"Пи", "=", "3.14", ",", "e", "=", "2,71", ";", "всего", "1 000 000", "руб", ".", "в", "2019", "100", "раз"}},
+		{"Пишите на info@example.com или https://example.com/a?b=1.", []string{"Пишите", "на", "info@example.com", "или", "https://example.com/a?b=1", "."}},
+		{"(см. www.example.com/wiki_(x))", []string{"(", "см", ".", "www.example.com/wiki_(x))"}},
+		{"Привет :-) как дела?! Отлично:))) xD", []string{"Привет", ":-)", "как", "дела", "?!", "Отлично", ":)))", "xD"}},
+		{"2-й этап, 10 % и $5", []string{"2-й", "этап", ",", "10", "%", "и", "$", "5"}},
+		{"", nil},
+	} {
+		var tokens []string
+		runes := []rune(c.text)
+		for _, token := range tokenizer.Tokenize(c.text) {
+			if string(runes[token.Start:token.End]) != token.Text {
+				t.Errorf("%q: span %d:%d is not %q", c.text, token.Start, token.End, token.Text)
+			}
+			tokens = append(tokens, token.Text)
+		}
+		if !reflect.DeepEqual(tokens, c.tokens) {
+			t.Errorf("%q: expected %q got %q", c.text, c.tokens, tokens)
+		}
+	}
+}
+
+func TestWordTokenizerRules(t *testing.T) {
+	tokenizer := &WordTokenizer{Protected: []*regexp.Regexp{regexp.MustCompile(`#[\pL\d_]+`)}}
+	var tokens []string
+	for _, token := range tokenizer.Tokenize("что-то #тег 3.14 :)") {
+		tokens = append(tokens, token.Text)
+	}
+	expected := []string{"что", "-", "то", "#тег", "3", ".", "14", ":", ")"}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("expected %q got %q", expected, tokens)
+	}
+}
+
+func TestWordTokenizerAllocs(t *testing.T) {
+	tokenizer := NewWordTokenizer()
+	text := "Пишите на info@example.com: цена 1 000,50 руб., что-то :-) https://example.com"
+	count := 0
+	allocs := testing.AllocsPerRun(100, func() {
+		tokenizer.Each(text, func(token string, start, end int) {
+			count++
+		})
+	})
+	if allocs > 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+}
+
+func BenchmarkWordTokenizer(b *testing.B) {
+	tokenizer := NewWordTokenizer()
+	text := "Поэт А. С. Пушкин родился в 1799 г. в Москве, а умер — в Санкт-Петербурге; см. https://ru.wikipedia.org :-)"
+	b.SetBytes(int64(len(text)))
+	for i := 0; i < b.N; i++ {
+		tokenizer.Each(text, func(token string, start, end int) {})
+	}
+}
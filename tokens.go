@@ -0,0 +1,279 @@
+package gorpora
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/vseledkin/gorpora/conllu"
+	"github.com/vseledkin/gorpora/tokenizer"
+	"github.com/vseledkin/gorpora/udpipe"
+)
+
+// Output formats of Split.
+const (
+	// FormatText outputs space separated words or lemmas, one line per input line.
+	FormatText = "text"
+	// FormatCoNLLU outputs udpipe analysis in CoNLL-U format.
+	FormatCoNLLU = "conllu"
+	// FormatJSONL outputs one JSON object with sentences and tokens per input line.
+	FormatJSONL = "jsonl"
+	// FormatTSV outputs a token per line and a blank line after each sentence,
+	// columns are word, lemma, pos, fine pos, features, head, relation,
+	// start and end offsets.
+	FormatTSV = "tsv"
+)
+
+// TokenFilter selects tokens Split outputs in text format.
+type TokenFilter struct {
+	// KeepPos lists universal or language specific POS tags of output
+	// tokens, all tokens are output if it is empty. Tags may also be
+	// given as comma separated lists.
+	KeepPos []string
+	// DropPos lists POS tags of tokens not to output.
+	DropPos []string
+	// Features lists features output tokens must have, either as
+	// Name=Value or as Name when any value is accepted.
+	Features []string
+	// WithPos appends universal POS tag to output tokens, e.g. кошка_NOUN.
+	WithPos bool
+}
+
+// empty tells if the filter outputs all tokens as is.
+func (f *TokenFilter) empty() bool {
+	return f == nil || len(f.KeepPos) == 0 && len(f.DropPos) == 0 && len(f.Features) == 0 && !f.WithPos
+}
+
+// posSet returns set of tags given as separate or comma separated values.
+func posSet(tags []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, tag := range tags {
+		for _, t := range strings.Split(tag, ",") {
+			if t = strings.TrimSpace(t); len(t) > 0 {
+				set[t] = true
+			}
+		}
+	}
+	return set
+}
+
+// tokenMatcher is TokenFilter prepared for matching.
+type tokenMatcher struct {
+	keep, drop map[string]bool
+	features   map[string]string
+}
+
+func newTokenMatcher(f *TokenFilter) *tokenMatcher {
+	m := &tokenMatcher{features: make(map[string]string)}
+	if f == nil {
+		return m
+	}
+	m.keep, m.drop = posSet(f.KeepPos), posSet(f.DropPos)
+	for _, feature := range f.Features {
+		name, value := feature, ""
+		if i := strings.Index(feature, "="); i >= 0 {
+			name, value = feature[:i], feature[i+1:]
+		}
+		m.features[name] = value
+	}
+	return m
+}
+
+// match tells if token passes the filter.
+func (m *tokenMatcher) match(token *udpipe.Token) bool {
+	if len(m.keep) > 0 && !m.keep[token.Pos] && !m.keep[token.FinePos] {
+		return false
+	}
+	if m.drop[token.Pos] || m.drop[token.FinePos] {
+		return false
+	}
+	for name, value := range m.features {
+		if v, ok := token.Features[name]; !ok || len(value) > 0 && v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// sentenceWriter writes sentences parsed by udpipe in one of output formats.
+type sentenceWriter struct {
+	format  string
+	lemmas  bool
+	withPos bool
+	tokens  *tokenMatcher
+	out     *bufio.Writer
+	conllu  *conllu.Writer
+	// sentences numbers output sentences, udpipe processes of a pool
+	// number them independently.
+	sentences int
+}
+
+func newSentenceWriter(w io.Writer, format string, lemmas bool, filter *TokenFilter) (*sentenceWriter, error) {
+	switch format {
+	case "", FormatText:
+	case FormatCoNLLU, FormatJSONL, FormatTSV:
+		if !filter.empty() {
+			return nil, fmt.Errorf("token filters are not supported by %s format", format)
+		}
+	default:
+		return nil, fmt.Errorf("unknown format %q, expected %s, %s, %s or %s", format, FormatText, FormatCoNLLU, FormatJSONL, FormatTSV)
+	}
+	out := bufio.NewWriter(w)
+	return &sentenceWriter{
+		format:  format,
+		lemmas:  lemmas,
+		withPos: filter != nil && filter.WithPos,
+		tokens:  newTokenMatcher(filter),
+		out:     out,
+		conllu:  conllu.NewWriter(out),
+	}, nil
+}
+
+type jsonToken struct {
+	ID         int               `json:"id"`
+	Word       string            `json:"form"`
+	Lemma      string            `json:"lemma"`
+	Pos        string            `json:"upos"`
+	FinePos    string            `json:"xpos,omitempty"`
+	Features   map[string]string `json:"feats,omitempty"`
+	Head       int               `json:"head"`
+	Function   string            `json:"deprel"`
+	Start      int               `json:"start"`
+	End        int               `json:"end"`
+	SpaceAfter bool              `json:"space_after"`
+}
+
+type jsonSentence struct {
+	Text   string      `json:"text"`
+	Tokens []jsonToken `json:"tokens"`
+}
+
+type jsonLine struct {
+	Text      string         `json:"text"`
+	Sentences []jsonSentence `json:"sentences"`
+}
+
+// tokenize splits text into sentences and words with native tokenizers,
+// tokens have offsets but no other annotation.
+func tokenize(sentences *tokenizer.SentenceSplitter, words *tokenizer.WordTokenizer, text string) []*udpipe.Sentence {
+	var result []*udpipe.Sentence
+	runes := []rune(text)
+	for _, span := range sentences.Split(text) {
+		sentence := &udpipe.Sentence{ID: len(result) + 1, Body: span.Text}
+		words.Each(span.Text, func(word string, start, end int) {
+			token := &udpipe.Token{
+				ID:         len(sentence.Tokens) + 1,
+				Dependency: -1,
+				Word:       word,
+				Start:      span.Start + start,
+				End:        span.Start + end,
+				SpaceAfter: true,
+			}
+			if token.End < len(runes) && !unicode.IsSpace(runes[token.End]) {
+				token.SpaceAfter = false
+				token.Misc = "SpaceAfter=No"
+			}
+			sentence.Tokens = append(sentence.Tokens, token)
+		})
+		result = append(result, sentence)
+	}
+	return result
+}
+
+// write writes sentences parsed from line.
+func (w *sentenceWriter) write(line string, sentences []*udpipe.Sentence) error {
+	switch w.format {
+	case FormatCoNLLU:
+		for _, sentence := range sentences {
+			w.sentences++
+			sentence.ID = w.sentences
+			if e := w.conllu.Write(sentence); e != nil {
+				return e
+			}
+		}
+		return w.conllu.Flush()
+	case FormatJSONL:
+		object := jsonLine{Text: line, Sentences: make([]jsonSentence, len(sentences))}
+		for i, sentence := range sentences {
+			object.Sentences[i].Text = sentence.Body
+			for _, token := range sentence.Tokens {
+				finePos := token.FinePos
+				if finePos == "_" {
+					finePos = ""
+				}
+				object.Sentences[i].Tokens = append(object.Sentences[i].Tokens, jsonToken{
+					ID:         token.ID,
+					Word:       token.Word,
+					Lemma:      token.Lemma,
+					Pos:        token.Pos,
+					FinePos:    finePos,
+					Features:   token.Features,
+					Head:       token.Dependency,
+					Function:   token.Function,
+					Start:      token.Start,
+					End:        token.End,
+					SpaceAfter: token.SpaceAfter,
+				})
+			}
+		}
+		bits, e := json.Marshal(&object)
+		if e != nil {
+			return e
+		}
+		w.out.Write(bits)
+	case FormatTSV:
+		for _, sentence := range sentences {
+			for _, token := range sentence.Tokens {
+				fmt.Fprintf(w.out, "%s\t%s\t%s\t%s\t%s\t%d\t%s\t%d\t%d\n", token.Word, token.Lemma, token.Pos,
+					token.FinePos, udpipe.FormatFeatures(token.Features), token.Dependency, token.Function, token.Start, token.End)
+			}
+			w.out.WriteString("\n")
+		}
+		return nil
+	default:
+		var tokens []string
+		for _, sentence := range sentences {
+			for _, token := range sentence.Tokens {
+				if !w.tokens.match(token) {
+					continue
+				}
+				word := token.Word
+				if w.lemmas {
+					word = token.Lemma
+				}
+				word = unbreakable(word)
+				if w.withPos {
+					word += "_" + token.Pos
+				}
+				tokens = append(tokens, word)
+			}
+		}
+		w.out.WriteString(strings.Join(tokens, " "))
+	}
+	_, e := w.out.WriteString("\n")
+	return e
+}
+
+func (w *sentenceWriter) flush() error {
+	return w.out.Flush()
+}
+
+// unbreakable replaces spaces inside tokens like 1 000 with no-break
+// space so tokens stay separated by spaces.
+func unbreakable(token string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return '\u00a0'
+		}
+		return r
+	}, token)
+}
+
+// fields splits line into tokens separated by spaces, tabs and line
+// ends, no-break spaces belong to tokens.
+func fields(line string) []string {
+	return strings.FieldsFunc(line, func(r rune) bool { return r == ' ' || r == '\t' || r == '\r' || r == '\n' })
+}
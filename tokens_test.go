@@ -0,0 +1,115 @@
+package gorpora
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/vseledkin/gorpora/tokenizer"
+	"github.com/vseledkin/gorpora/udpipe"
+)
+
+// testSentence returns sentence made of CoNLL-U lines.
+func testSentence(t *testing.T, body string, lines ...string) *udpipe.Sentence {
+	sentence := &udpipe.Sentence{ID: 7, Body: body}
+	for _, line := range lines {
+		token, e := udpipe.ParseToken(line)
+		if e != nil {
+			t.Fatal(e)
+		}
+		sentence.AddToken(token)
+	}
+	return sentence
+}
+
+func TestSentenceWriter(t *testing.T) {
+	sentence := testSentence(t, "Кот спит",
+		"1\tКот\tкот\tNOUN\t_\tCase=Nom\t2\tnsubj\t_\tTokenRange=0:3",
+		"2\tспит\tспать\tVERB\t_\t_\t0\troot\t_\tSpaceAfter=No|TokenRange=4:8",
+	)
+	for _, c := range []struct {
+		format, expected string
+	}{
+		{FormatText, "Кот спит\n"},
+		{FormatTSV, "Кот\tкот\tNOUN\t_\tCase=Nom\t2\tnsubj\t0\t3\nспит\tспать\tVERB\t_\t_\t0\troot\t4\t8\n\n"},
+		{FormatJSONL, `{"text":"Кот спит","sentences":[{"text":"Кот спит","tokens":[` +
+			`{"id":1,"form":"Кот","lemma":"кот","upos":"NOUN","feats":{"Case":"Nom"},"head":2,"deprel":"nsubj","start":0,"end":3,"space_after":true},` +
+			`{"id":2,"form":"спит","lemma":"спать","upos":"VERB","head":0,"deprel":"root","start":4,"end":8,"space_after":false}]}]}` + "\n"},
+		{FormatCoNLLU, "# sent_id = 1\n# text = Кот спит\n" +
+			"1\tКот\tкот\tNOUN\t_\tCase=Nom\t2\tnsubj\t_\tTokenRange=0:3\n" +
+			"2\tспит\tспать\tVERB\t_\t_\t0\troot\t_\tSpaceAfter=No|TokenRange=4:8\n\n"},
+	} {
+		var b bytes.Buffer
+		w, e := newSentenceWriter(&b, c.format, false, nil)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if e = w.write("Кот спит", []*udpipe.Sentence{sentence}); e != nil {
+			t.Fatal(e)
+		}
+		w.flush()
+		if b.String() != c.expected {
+			t.Errorf("%s: expected\n%q\ngot\n%q", c.format, c.expected, b.String())
+		}
+	}
+}
+
+func TestTokenFilter(t *testing.T) {
+	sentence := testSentence(t, "Серая кошка видит кошек.",
+		"1\tСерая\tсерый\tADJ\t_\tCase=Nom|Gender=Fem\t2\tamod\t_\t_",
+		"2\tкошка\tкошка\tNOUN\t_\tCase=Nom|Gender=Fem\t3\tnsubj\t_\t_",
+		"3\tвидит\tвидеть\tVERB\t_\tTense=Pres\t0\troot\t_\t_",
+		"4\tкошек\tкошка\tNOUN\t_\tCase=Acc|Gender=Fem\t3\tobj\t_\tSpaceAfter=No",
+		"5\t.\t.\tPUNCT\t_\t_\t3\tpunct\t_\t_",
+	)
+	for _, c := range []struct {
+		filter   TokenFilter
+		expected string
+	}{
+		{TokenFilter{WithPos: true}, "серый_ADJ кошка_NOUN видеть_VERB кошка_NOUN ._PUNCT\n"},
+		{TokenFilter{KeepPos: []string{"NOUN,VERB"}}, "кошка видеть кошка\n"},
+		{TokenFilter{DropPos: []string{"PUNCT", "ADJ"}}, "кошка видеть кошка\n"},
+		{TokenFilter{Features: []string{"Case=Nom"}, WithPos: true}, "серый_ADJ кошка_NOUN\n"},
+		{TokenFilter{KeepPos: []string{"NOUN"}, Features: []string{"Case"}}, "кошка кошка\n"},
+	} {
+		var b bytes.Buffer
+		w, e := newSentenceWriter(&b, FormatText, true, &c.filter)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if e = w.write(sentence.Body, []*udpipe.Sentence{sentence}); e != nil {
+			t.Fatal(e)
+		}
+		w.flush()
+		if b.String() != c.expected {
+			t.Errorf("%+v: expected %q got %q", c.filter, c.expected, b.String())
+		}
+	}
+	if _, e := newSentenceWriter(new(bytes.Buffer), FormatTSV, false, &TokenFilter{WithPos: true}); e == nil {
+		t.Error("expected filters to be rejected by tsv format")
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	splitter, e := tokenizer.NewSentenceSplitter("")
+	if e != nil {
+		t.Fatal(e)
+	}
+	sentences := tokenize(splitter, tokenizer.NewWordTokenizer(), "Цена 1 000 рублей. Дорого!")
+	if len(sentences) != 2 {
+		t.Fatalf("expected 2 sentences got %d", len(sentences))
+	}
+	if text := udpipe.Detokenize(sentences); text != "Цена 1 000 рублей. Дорого!" {
+		t.Errorf("unexpected detokenization %q", text)
+	}
+	if last := sentences[1].Tokens[1]; last.Word != "!" || last.Start != 25 || last.End != 26 || last.ID != 2 {
+		t.Errorf("unexpected token %+v", last)
+	}
+}
+
+func TestFields(t *testing.T) {
+	expected := []string{"1\u00a0000", "руб", "."}
+	if tokens := fields(unbreakable("1 000") + " руб\t.\r\n"); !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("expected %q got %q", expected, tokens)
+	}
+}
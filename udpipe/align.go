@@ -0,0 +1,102 @@
+package udpipe
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Align sets Start and End rune offsets and SpaceAfter flag of every
+// token of sentences parsed from text. Words of multiword tokens get
+// offsets of the multiword token.
+func Align(text string, sentences []*Sentence) {
+	runes := []rune(text)
+	pos := 0
+	align := func(token *Token) {
+		for pos < len(runes) && unicode.IsSpace(runes[pos]) {
+			pos++
+		}
+		word := []rune(token.Word)
+		start := indexRunes(runes, word, pos)
+		if start < 0 {
+			token.Start, token.End = -1, -1
+			return
+		}
+		token.Start, token.End = start, start+len(word)
+		if token.End < len(runes) {
+			token.SpaceAfter = unicode.IsSpace(runes[token.End])
+		}
+		pos = token.End
+	}
+	for _, sentence := range sentences {
+		multiwords := sentence.Multiwords
+		var multiword *Token
+		for _, token := range sentence.Tokens {
+			if len(multiwords) > 0 && multiwords[0].ID <= token.ID {
+				multiword = multiwords[0]
+				multiwords = multiwords[1:]
+				align(multiword)
+			}
+			if multiword != nil && token.ID <= multiword.Last {
+				token.Start, token.End = multiword.Start, multiword.End
+				token.SpaceAfter = token.ID == multiword.Last && multiword.SpaceAfter
+				continue
+			}
+			align(token)
+		}
+	}
+}
+
+// indexRunes returns index of word in text at or after from, -1 if absent.
+func indexRunes(text, word []rune, from int) int {
+	if len(word) == 0 {
+		return -1
+	}
+	for i := from; i+len(word) <= len(text); i++ {
+		if text[i] != word[0] {
+			continue
+		}
+		if string(text[i:i+len(word)]) == string(word) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Detokenize joins the surface forms of sentence tokens separated by
+// spaces where SpaceAfter is set, multiword tokens are output once.
+func (s *Sentence) Detokenize() string {
+	var b strings.Builder
+	s.detokenize(&b)
+	return strings.TrimRightFunc(b.String(), unicode.IsSpace)
+}
+
+func (s *Sentence) detokenize(b *strings.Builder) {
+	multiwords := s.Multiwords
+	var multiword *Token
+	for _, token := range s.Tokens {
+		if len(multiwords) > 0 && multiwords[0].ID <= token.ID {
+			multiword = multiwords[0]
+			multiwords = multiwords[1:]
+			b.WriteString(multiword.Word)
+			if multiword.SpaceAfter {
+				b.WriteByte(' ')
+			}
+		}
+		if multiword != nil && token.ID <= multiword.Last {
+			continue
+		}
+		b.WriteString(token.Word)
+		if token.SpaceAfter {
+			b.WriteByte(' ')
+		}
+	}
+}
+
+// Detokenize joins detokenized sentences into text.
+func Detokenize(sentences []*Sentence) string {
+	var b strings.Builder
+	for _, sentence := range sentences {
+		sentence.detokenize(&b)
+	}
+	return strings.TrimRightFunc(b.String(), unicode.IsSpace)
+}
@@ -0,0 +1,166 @@
+package udpipe
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AddToken appends token, multiword token or empty node to the sentence.
+func (s *Sentence) AddToken(token *Token) {
+	switch {
+	case token.Last > 0:
+		s.Multiwords = append(s.Multiwords, token)
+	case token.Empty > 0:
+		s.EmptyNodes = append(s.EmptyNodes, token)
+	default:
+		s.Tokens = append(s.Tokens, token)
+	}
+}
+
+// ParseToken parses CoNLL-U word line of ten tab separated columns
+// ID FORM LEMMA UPOS XPOS FEATS HEAD DEPREL DEPS MISC, trailing
+// DEPS and MISC columns may be omitted.
+func ParseToken(line string) (token *Token, err error) {
+	columns := strings.Split(line, "\t")
+	if len(columns) < 8 {
+		return nil, fmt.Errorf("expected 10 columns got %d", len(columns))
+	}
+	token = &Token{
+		Word:     columns[1],
+		Lemma:    columns[2],
+		Pos:      columns[3],
+		FinePos:  columns[4],
+		Features: ParseFeatures(columns[5]),
+		Function: columns[7],
+		Deps:     "_",
+		Misc:     "_",
+	}
+	if len(columns) > 8 {
+		token.Deps = columns[8]
+	}
+	if len(columns) > 9 {
+		token.Misc = columns[9]
+	}
+	token.parseMisc()
+	if err = token.parseID(columns[0]); err != nil {
+		return nil, err
+	}
+	if columns[6] == "_" {
+		token.Dependency = -1
+	} else if token.Dependency, err = strconv.Atoi(columns[6]); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (t *Token) parseID(id string) (err error) {
+	if i := strings.IndexByte(id, '-'); i > 0 {
+		if t.ID, err = strconv.Atoi(id[:i]); err != nil {
+			return err
+		}
+		t.Last, err = strconv.Atoi(id[i+1:])
+		return err
+	}
+	if i := strings.IndexByte(id, '.'); i > 0 {
+		if t.ID, err = strconv.Atoi(id[:i]); err != nil {
+			return err
+		}
+		t.Empty, err = strconv.Atoi(id[i+1:])
+		return err
+	}
+	t.ID, err = strconv.Atoi(id)
+	return err
+}
+
+// parseMisc sets SpaceAfter and TokenRange offsets from MISC column.
+func (t *Token) parseMisc() {
+	t.SpaceAfter = true
+	t.Start, t.End = -1, -1
+	for _, item := range strings.Split(t.Misc, "|") {
+		switch {
+		case item == "SpaceAfter=No":
+			t.SpaceAfter = false
+		case strings.HasPrefix(item, "TokenRange="):
+			bounds := strings.SplitN(item[len("TokenRange="):], ":", 2)
+			if len(bounds) != 2 {
+				continue
+			}
+			start, e1 := strconv.Atoi(bounds[0])
+			end, e2 := strconv.Atoi(bounds[1])
+			if e1 == nil && e2 == nil {
+				t.Start, t.End = start, end
+			}
+		}
+	}
+}
+
+// ParseFeatures parses FEATS column like "Case=Nom|Number=Sing",
+// "_" gives nil.
+func ParseFeatures(column string) map[string]string {
+	if column == "_" || column == "" {
+		return nil
+	}
+	features := make(map[string]string)
+	for _, feature := range strings.Split(column, "|") {
+		featureParts := strings.SplitN(feature, "=", 2)
+		if len(featureParts) == 2 {
+			features[featureParts[0]] = featureParts[1]
+		}
+	}
+	return features
+}
+
+// FormatFeatures formats features as FEATS column sorted by name
+// case insensitively as UD requires.
+func FormatFeatures(features map[string]string) string {
+	if len(features) == 0 {
+		return "_"
+	}
+	names := make([]string, 0, len(features))
+	for name := range features {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
+	})
+	for i, name := range names {
+		names[i] = name + "=" + features[name]
+	}
+	return strings.Join(names, "|")
+}
+
+// String returns the token as CoNLL-U word line.
+func (t *Token) String() string {
+	id := strconv.Itoa(t.ID)
+	switch {
+	case t.Last > 0:
+		id += "-" + strconv.Itoa(t.Last)
+	case t.Empty > 0:
+		id += "." + strconv.Itoa(t.Empty)
+	}
+	head := "_"
+	if t.Dependency >= 0 {
+		head = strconv.Itoa(t.Dependency)
+	}
+	return strings.Join([]string{
+		id,
+		t.Word,
+		orUnderscore(t.Lemma),
+		orUnderscore(t.Pos),
+		orUnderscore(t.FinePos),
+		FormatFeatures(t.Features),
+		head,
+		orUnderscore(t.Function),
+		orUnderscore(t.Deps),
+		orUnderscore(t.Misc),
+	}, "\t")
+}
+
+func orUnderscore(column string) string {
+	if column == "" {
+		return "_"
+	}
+	return column
+}
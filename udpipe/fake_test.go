@@ -0,0 +1,113 @@
+package udpipe
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeEnv makes test binary act as udpipe process, see fakeUdpipe.
+const fakeEnv = "GORPORA_FAKE_UDPIPE"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeEnv) != "" {
+		fakeUdpipe()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeConfig returns config running test binary as fake udpipe.
+func fakeConfig(t *testing.T) ParserConfig {
+	os.Setenv(fakeEnv, "1")
+	t.Cleanup(func() {
+		os.Unsetenv(fakeEnv)
+	})
+	return ParserConfig{Binary: os.Args[0], Model: "fake.udpipe"}
+}
+
+// fakeCrashEnv names file fake udpipe creates when it crashes on CRASHONCE.
+const fakeCrashEnv = "GORPORA_FAKE_UDPIPE_CRASHED"
+
+// fakeUdpipe reads paragraphs separated by blank lines and writes them
+// back in CoNLL-U format like udpipe --immediate does, tokens are split
+// on spaces and sentences end with tokens ending with a dot. Words BADID
+// and BADLEN produce broken token id and too long sentence text, SHORTLEN
+// is dropped from sentence text, CRASH makes the process exit with
+// status 3 and CRASHONCE does it unless file named by fakeCrashEnv
+// exists, HANG makes it stop responding. Model
+// missing.udpipe fails to load and hang.udpipe never finishes loading.
+func fakeUdpipe() {
+	switch model := os.Args[len(os.Args)-1]; model {
+	case "missing.udpipe":
+		fmt.Fprintf(os.Stderr, "Loading UDPipe model: Cannot load UDPipe model '%s'!\n", model)
+		os.Exit(1)
+	case "hang.udpipe":
+		fmt.Fprint(os.Stderr, "Loading UDPipe model: ")
+		time.Sleep(time.Hour)
+	}
+	fmt.Fprintln(os.Stderr, "Loading UDPipe model: done.")
+	scanner := bufio.NewScanner(os.Stdin)
+	out := bufio.NewWriter(os.Stdout)
+	sentenceID := 0
+	var paragraph []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(strings.TrimSpace(line)) > 0 {
+			paragraph = append(paragraph, strings.Fields(line)...)
+			continue
+		}
+		for _, word := range paragraph {
+			if word == "CRASH" {
+				os.Exit(3)
+			}
+			if word == "HANG" {
+				time.Sleep(time.Hour)
+			}
+			if crashed := os.Getenv(fakeCrashEnv); word == "CRASHONCE" && crashed != "" {
+				if _, e := os.Stat(crashed); os.IsNotExist(e) {
+					os.Create(crashed)
+					os.Exit(3)
+				}
+			}
+		}
+		if len(paragraph) == 0 {
+			continue
+		}
+		fmt.Fprintln(out, "# newdoc")
+		fmt.Fprintln(out, "# newpar")
+		var sentence []string
+		for i, word := range paragraph {
+			sentence = append(sentence, word)
+			if !strings.HasSuffix(word, ".") && i < len(paragraph)-1 {
+				continue
+			}
+			sentenceID++
+			fmt.Fprintf(out, "# sent_id = %d\n", sentenceID)
+			text := strings.Join(sentence, " ")
+			if strings.Contains(text, "BADLEN") {
+				text += "extra"
+			}
+			text = strings.Replace(text, "SHORTLEN", "", -1)
+			fmt.Fprintf(out, "# text = %s\n", text)
+			for j, w := range sentence {
+				head := 0
+				if j > 0 {
+					head = 1
+				}
+				id := fmt.Sprint(j + 1)
+				if w == "BADID" {
+					id = "x"
+				}
+				fmt.Fprintf(out, "%s\t%s\t%s\tX\t_\t_\t%d\tdep\t_\t_\n", id, w, strings.ToLower(w), head)
+			}
+			fmt.Fprintln(out)
+			sentence = nil
+		}
+		paragraph = nil
+		out.Flush()
+	}
+}
@@ -0,0 +1,84 @@
+package udpipe
+
+import (
+	"context"
+	"sync"
+)
+
+// ParserPool runs several udpipe processes and hands every Parse
+// call to the first idle one.
+type ParserPool struct {
+	parsers []*Parser
+	idle    chan *Parser
+}
+
+// NewParserPool returns pool of size parsers which run udpipe with given
+// config once started.
+func NewParserPool(config ParserConfig, size int) *ParserPool {
+	if size < 1 {
+		size = 1
+	}
+	pool := &ParserPool{idle: make(chan *Parser, size)}
+	for i := 0; i < size; i++ {
+		pool.parsers = append(pool.parsers, NewParser(config))
+	}
+	return pool
+}
+
+// Start launches all udpipe processes in parallel and waits for them
+// to load the model.
+func (p *ParserPool) Start() error {
+	errs := make([]error, len(p.parsers))
+	var w sync.WaitGroup
+	for i, parser := range p.parsers {
+		w.Add(1)
+		go func(i int, parser *Parser) {
+			defer w.Done()
+			errs[i] = parser.Start()
+		}(i, parser)
+	}
+	w.Wait()
+	for i, e := range errs {
+		if e != nil {
+			p.Close()
+			return e
+		}
+		p.idle <- p.parsers[i]
+	}
+	return nil
+}
+
+// Parse parses text with the first idle parser of the pool.
+func (p *ParserPool) Parse(text string) ([]*Sentence, error) {
+	return p.ParseContext(context.Background(), text)
+}
+
+// ParseContext parses text with the first idle parser of the pool giving
+// up once ctx is done, see Parser.ParseContext.
+func (p *ParserPool) ParseContext(ctx context.Context, text string) ([]*Sentence, error) {
+	var parser *Parser
+	select {
+	case parser = <-p.idle:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() {
+		p.idle <- parser
+	}()
+	return parser.ParseContext(ctx, text)
+}
+
+// Size returns the number of parsers in the pool.
+func (p *ParserPool) Size() int {
+	return len(p.parsers)
+}
+
+// Close stops all udpipe processes, the first failed exit is returned.
+func (p *ParserPool) Close() (err error) {
+	for _, parser := range p.parsers {
+		if e := parser.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
@@ -1,17 +1,20 @@
 package udpipe
 
 import (
+	"context"
 	"os/exec"
 
 	"log"
 
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"runtime"
 	"strconv"
 	"strings"
-	"sync"
+	"time"
 	"unicode"
 )
 
@@ -19,19 +22,48 @@ type Sentence struct {
 	ID     int
 	Body   string
 	Tokens []*Token
+	// Multiwords are multiword tokens like "1-2", each placed
+	// before the token with the same ID.
+	Multiwords []*Token
+	// EmptyNodes are empty nodes like "3.1", each placed
+	// after the token with the same ID.
+	EmptyNodes []*Token
+	// Comments are raw comment lines preceding the sentence,
+	// including "# sent_id" and "# text" ones, as read from CoNLL-U file.
+	Comments []string
 }
 
+// MakeDependencies links tokens to their head tokens, DependencyToken is
+// nil for root and for tokens whose head is not among sentence tokens.
 func (s *Sentence) MakeDependencies() {
 	for _, token := range s.Tokens {
+		token.DependencyToken = s.token(token.Dependency)
+	}
+}
 
-		if token.Dependency > 0 {
-			token.DependencyToken = s.Tokens[token.Dependency-1]
+// token returns sentence token with given ID or nil.
+func (s *Sentence) token(id int) *Token {
+	if id <= 0 {
+		return nil
+	}
+	if id <= len(s.Tokens) && s.Tokens[id-1].ID == id {
+		return s.Tokens[id-1]
+	}
+	for _, token := range s.Tokens {
+		if token.ID == id {
+			return token
 		}
 	}
+	return nil
 }
 
 type Token struct {
-	ID              int
+	ID int
+	// Last is the ID of the last word of multiword token, 0 otherwise.
+	Last int
+	// Empty is the decimal part of empty node ID, 0 otherwise.
+	Empty int
+	// Dependency is the ID of head token, 0 for root, -1 if not given.
 	Dependency      int
 	Word            string
 	Lemma           string
@@ -39,65 +71,238 @@ type Token struct {
 	FinePos         string
 	Features        map[string]string
 	Function        string
+	Deps            string
+	Misc            string
 	DependencyToken *Token
+	// Start and End are rune offsets of the token in the parsed text,
+	// End is exclusive, both are -1 if the token is not found in text.
+	Start int
+	End   int
+	// SpaceAfter tells if the token is followed by space in text.
+	SpaceAfter bool
+}
+
+// ParserConfig tells where to find UDPipe and how to run it.
+type ParserConfig struct {
+	// Binary is the path of udpipe executable, ./udpipe/udpipe_<os>_<arch> by default.
+	Binary string
+	// Model is the path of udpipe model, Russian model by default.
+	Model string
+	// Flags are extra command line flags passed to udpipe.
+	Flags []string
+	// Dir is the working directory of udpipe process, relative
+	// Binary and Model paths are resolved against it.
+	Dir string
+	// LoadTimeout is the time udpipe has to load the model,
+	// DefaultLoadTimeout if zero.
+	LoadTimeout time.Duration
+}
+
+// DefaultModel is the model used when none is configured.
+const DefaultModel = "./udpipe/russian-ud-2.0-170801.udpipe"
+
+// DefaultLoadTimeout is the time udpipe has to load the model when
+// none is configured.
+const DefaultLoadTimeout = 2 * time.Minute
+
+// DefaultBinary returns path of the udpipe executable bundled for current platform.
+func DefaultBinary() string {
+	return fmt.Sprintf("./udpipe/udpipe_%s_%s", runtime.GOOS, runtime.GOARCH)
+}
+
+func (c ParserConfig) binary() string {
+	if c.Binary == "" {
+		return DefaultBinary()
+	}
+	return c.Binary
+}
+
+func (c ParserConfig) model() string {
+	if c.Model == "" {
+		return DefaultModel
+	}
+	return c.Model
+}
+
+func (c ParserConfig) loadTimeout() time.Duration {
+	if c.LoadTimeout <= 0 {
+		return DefaultLoadTimeout
+	}
+	return c.LoadTimeout
+}
+
+func (c ParserConfig) command() *exec.Cmd {
+	args := []string{"--tokenize", "--tag", "--parse", "--immediate"}
+	args = append(args, c.Flags...)
+	args = append(args, c.model())
+	cmd := exec.Command(c.binary(), args...)
+	cmd.Dir = c.Dir
+	return cmd
 }
 
 type Parser struct {
-	stdout  io.ReadCloser
-	stdin   io.WriteCloser
-	stderr  io.ReadCloser
+	Config ParserConfig
+	// Restarts counts udpipe processes restarted after failure.
+	Restarts int
+	proc     *process
+	syncs    int
+	licence  chan struct{}
+}
+
+// process is a running udpipe.
+type process struct {
 	cmd     *exec.Cmd
-	licence chan struct{}
+	stdin   io.WriteCloser
+	stdout  io.ReadCloser
+	scanner *bufio.Scanner
+	// exited is closed once the process exits, status is then the
+	// result of cmd.Wait.
+	exited chan struct{}
+	status error
+}
+
+// maxLineSize limits the size of udpipe output line.
+const maxLineSize = 16 * 1024 * 1024
+
+// closeTimeout is the time udpipe has to exit once its input is closed.
+const closeTimeout = 10 * time.Second
+
+// NewParser returns parser which runs udpipe with given config once started.
+func NewParser(config ParserConfig) *Parser {
+	return &Parser{Config: config}
 }
 
-func (p *Parser) Close() {
-	p.stdin.Close()
-	p.stdout.Close()
-	p.stderr.Close()
+// ExitError is returned when udpipe process exits or stops responding,
+// the process is restarted before the error is returned.
+type ExitError struct {
+	// Err is the failure noticed by parser.
+	Err error
+	// Status is the exit status of the process, nil if it exited with 0.
+	Status error
 }
 
-func (p *Parser) Start() (err error) {
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ExitError) Error() string {
+	status := "exit status 0"
+	if e.Status != nil {
+		status = e.Status.Error()
+	}
+	return fmt.Sprintf("udpipe: %v, process exited: %s", e.Err, status)
+}
+
+// errNotLoaded is reported when udpipe exits before loading the model.
+var errNotLoaded = errors.New("model is not loaded")
+
+// errLoadTimeout is reported when udpipe does not load the model in
+// LoadTimeout, the process is killed.
+var errLoadTimeout = errors.New("model is not loaded in time")
+
+// Close closes udpipe input and waits for the process to exit, it is
+// killed if it does not exit in closeTimeout. Exit status other than 0
+// is returned as error.
+func (p *Parser) Close() error {
+	if p.proc == nil {
+		return nil
+	}
+	<-p.licence
+	defer func() {
+		p.licence <- struct{}{}
+	}()
+	proc := p.proc
+	p.proc = nil
+	proc.stdin.Close()
+	go io.Copy(ioutil.Discard, proc.stdout) // let udpipe flush its output
+	select {
+	case <-proc.exited:
+	case <-time.After(closeTimeout):
+		proc.cmd.Process.Kill()
+		<-proc.exited
+	}
+	if proc.status != nil {
+		return &ExitError{Err: errors.New("closed"), Status: proc.status}
+	}
+	return nil
+}
+
+// Start runs udpipe and waits for it to load the model, the process is
+// killed if the model is not loaded in LoadTimeout.
+func (p *Parser) Start() error {
 	if p.licence == nil {
 		p.licence = make(chan struct{}, 1)
 		p.licence <- struct{}{}
 	}
-	os := runtime.GOOS
-	arch := runtime.GOARCH
-	ppath := fmt.Sprintf("./udpipe/udpipe_%s_%s", os, arch)
-	log.Printf("Starting %s parser\n", ppath)
-	//p.cmd = exec.Command(ppath, "--tokenize", "--tag", "--parse", "--immediate", "./udpipe/english-ud-2.0-conll17-170315.udpipe")
-	p.cmd = exec.Command(ppath, "--tokenize", "--tag", "--parse", "--immediate", "./udpipe/russian-ud-2.0-170801.udpipe")
-	p.stdin, err = p.cmd.StdinPipe()
-	if err != nil {
+	return p.start()
+}
+
+func (p *Parser) start() (err error) {
+	log.Printf("Starting %s parser with model %s\n", p.Config.binary(), p.Config.model())
+	proc := &process{cmd: p.Config.command(), exited: make(chan struct{})}
+	if proc.stdin, err = proc.cmd.StdinPipe(); err != nil {
 		return err
 	}
-
-	p.stdout, err = p.cmd.StdoutPipe()
-	if err != nil {
+	if proc.stdout, err = proc.cmd.StdoutPipe(); err != nil {
 		return err
 	}
-
-	p.stderr, err = p.cmd.StderrPipe()
+	proc.scanner = bufio.NewScanner(proc.stdout)
+	proc.scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	stderr, err := proc.cmd.StderrPipe()
 	if err != nil {
 		return err
 	}
-
-	// Start the process
-	if err = p.cmd.Start(); err != nil {
+	if err = proc.cmd.Start(); err != nil {
 		return err
 	}
-	var w sync.WaitGroup
-	w.Add(1)
-	go func() {
-		scanner := bufio.NewScanner(p.stderr)
-		for scanner.Scan() {
-			log.Printf("parser stderr-> %s\n", scanner.Text())
-			w.Done()
+	loaded := make(chan struct{})
+	go proc.watch(stderr, loaded)
+	timer := time.NewTimer(p.Config.loadTimeout())
+	defer timer.Stop()
+	select {
+	case <-loaded:
+		p.proc = proc
+		return nil
+	case <-proc.exited:
+		return &ExitError{Err: errNotLoaded, Status: proc.status}
+	case <-timer.C:
+		proc.stdin.Close()
+		proc.cmd.Process.Kill()
+		<-proc.exited
+		return &ExitError{Err: errLoadTimeout, Status: proc.status}
+	}
+}
+
+// watch logs udpipe stderr, closes loaded once the model is loaded and
+// waits for the process to exit when stderr is closed.
+func (proc *process) watch(stderr io.Reader, loaded chan struct{}) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		line := scanner.Text()
+		log.Printf("parser stderr-> %s\n", line)
+		if loaded != nil && strings.HasPrefix(line, "Loading UDPipe model:") && strings.HasSuffix(line, "done.") {
+			close(loaded)
+			loaded = nil
 		}
-	}()
-	w.Wait()
-	return nil
+	}
+	proc.status = proc.cmd.Wait()
+	close(proc.exited)
+}
+
+// restart kills udpipe process if it is still running and starts a new
+// one, the returned ExitError describes the failure.
+func (p *Parser) restart(err error) (*ExitError, error) {
+	proc := p.proc
+	p.proc = nil
+	proc.stdin.Close()
+	proc.cmd.Process.Kill()
+	<-proc.exited
+	exit := &ExitError{Err: err, Status: proc.status}
+	log.Printf("%v, restarting\n", exit)
+	p.Restarts++
+	return exit, p.start()
 }
+
 func LenWithoutSpaces(str string) int {
 	return len(strings.Map(func(r rune) rune {
 		if unicode.IsSpace(r) {
@@ -106,78 +311,205 @@ func LenWithoutSpaces(str string) int {
 		return r
 	}, str))
 }
-func (p *Parser) Parse(text string) (sentences []*Sentence, err error) {
-	<-p.licence
+
+// ErrNoSentence is returned when udpipe outputs a token or text line
+// before any "# sent_id" line.
+var ErrNoSentence = errors.New("udpipe: line outside of sentence")
+
+// FormatError is returned when udpipe outputs a line which cannot be parsed.
+type FormatError struct {
+	Line string
+	Err  error
+}
+
+func (e *FormatError) Error() string {
+	return fmt.Sprintf("udpipe: cannot parse line %q: %v", e.Line, e.Err)
+}
+
+// LengthError is returned when text of output sentences does not match
+// the input text, lengths are counted without spaces.
+type LengthError struct {
+	Input  int
+	Output int
+}
+
+func (e *LengthError) Error() string {
+	return fmt.Sprintf("udpipe: input length %d does not match output length %d", e.Input, e.Output)
+}
+
+// Parse sends text to udpipe and parses its CoNLL-U output. If the output
+// is unexpected the error is returned and the output stream is
+// resynchronized so the parser remains usable for next texts. If udpipe
+// fails it is restarted and the text is parsed once again, ExitError is
+// returned if the second attempt fails too.
+func (p *Parser) Parse(text string) ([]*Sentence, error) {
+	return p.ParseContext(context.Background(), text)
+}
+
+// ParseContext is like Parse but gives up once ctx is done. If it happens
+// while udpipe is parsing text, the process is killed and restarted and
+// ExitError wrapping ctx error is returned without retrying the text.
+func (p *Parser) ParseContext(ctx context.Context, text string) (sentences []*Sentence, err error) {
+	select {
+	case <-p.licence:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 	defer func() {
 		p.licence <- struct{}{}
 	}()
-	L := LenWithoutSpaces(text)
-	LL := 0
-	if _, err = p.stdin.Write([]byte(text + "\n\n")); err != nil {
-		println(err)
+	for retry := true; ; retry = false {
+		if p.proc == nil {
+			return nil, errors.New("udpipe: parser is not running")
+		}
+		sentences, err = p.parseContext(ctx, text)
+		if err == nil || isOutputError(err) {
+			return sentences, err
+		}
+		exit, e := p.restart(err)
+		if e != nil {
+			return nil, e
+		}
+		if !retry || ctx.Err() != nil {
+			return nil, exit
+		}
+	}
+}
+
+// parseContext parses text killing udpipe process once ctx is done.
+func (p *Parser) parseContext(ctx context.Context, text string) (sentences []*Sentence, err error) {
+	if ctx.Done() == nil {
+		return p.parse(text)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sentences, err = p.parse(text)
+	}()
+	select {
+	case <-done:
+		return sentences, err
+	case <-ctx.Done():
+		p.proc.cmd.Process.Kill()
+		<-done
+		return nil, ctx.Err()
+	}
+}
+
+// parse parses text with running udpipe process, text is followed by a
+// marker document whose output tells where output of text ends.
+func (p *Parser) parse(text string) (sentences []*Sentence, err error) {
+	marker := p.marker()
+	if _, err = p.proc.stdin.Write([]byte(text + "\n\n" + marker + "\n\n")); err != nil {
+		return nil, err
 	}
+	sentences, err = p.read(LenWithoutSpaces(text), marker)
+	if err == nil {
+		Align(text, sentences)
+	}
+	if isOutputError(err) {
+		if e := p.resync(); e != nil {
+			return nil, e
+		}
+	}
+	return
+}
+
+// isOutputError reports if err is caused by unexpected udpipe output
+// rather than by failure of the process.
+func isOutputError(err error) bool {
+	switch err.(type) {
+	case *FormatError, *LengthError:
+		return true
+	}
+	return err == ErrNoSentence
+}
 
-	//go func() {
-	scanner := bufio.NewScanner(p.stdout)
-	sentenceID := -1
-	var sentence *Sentence = nil
-Loop:
+// marker returns a new one word document marking end of output.
+func (p *Parser) marker() string {
+	p.syncs++
+	return fmt.Sprintf("gorporasync%d", p.syncs)
+}
+
+// read parses udpipe output up to the end of marker sentence, sentences
+// before it must have L non space chars.
+func (p *Parser) read(L int, marker string) (sentences []*Sentence, err error) {
+	LL := 0
+	var sentence *Sentence
+	end := false
+	scanner := p.proc.scanner
 	for scanner.Scan() {
 		line := scanner.Text()
-		//log.Printf("o-> [%s]\n", line)
 		switch {
 		case len(line) == 0:
 			if sentence == nil {
-				panic(fmt.Errorf("Sentence cannot be nil"))
+				return nil, ErrNoSentence
+			}
+			if end {
+				if LL != L {
+					return nil, &LengthError{Input: L, Output: LL}
+				}
+				return sentences, nil
 			}
 			sentences = append(sentences, sentence)
 			sentence = nil
-			//log.Printf("o-eos> L=%d LL=%d\n", L, LL)
-			if L == LL {
-				break Loop // end of sentence
+		case line == "# text = "+marker:
+			if sentence == nil {
+				return nil, ErrNoSentence
 			}
+			end = true
 		case line == "# newdoc":
 		case line == "# newpar":
 		case strings.HasPrefix(line, "# text = "):
+			if sentence == nil {
+				return nil, ErrNoSentence
+			}
 			sentence.Body = line[9:]
 			LL += LenWithoutSpaces(sentence.Body)
-		case sentence != nil: // parse token line
-			lineParts := strings.Fields(line)
-			token := &Token{}
-			token.ID, err = strconv.Atoi(lineParts[0])
-			if err != nil {
-				panic(err) // serious error parser will not be usable after this
-			}
-			token.Word = lineParts[1]
-			token.Lemma = strings.ToLower(lineParts[2])
-			token.Pos = lineParts[3]
-			token.FinePos = lineParts[4]
-			for _, feature := range strings.Split(lineParts[5], "|") {
-				if token.Features == nil {
-					token.Features = make(map[string]string)
-				}
-				featureParts := strings.Split(feature, "=")
-				if len(featureParts) == 2 {
-					token.Features[featureParts[0]] = featureParts[1]
-				}
-			}
-			token.Dependency, err = strconv.Atoi(lineParts[6])
-			if err != nil {
-				panic(err) // serious error parser will not be usable after this
-			}
-			token.Function = lineParts[7]
-			sentence.Tokens = append(sentence.Tokens, token)
 		case strings.HasPrefix(line, "# sent_id ="):
-			sentenceID, err = strconv.Atoi(strings.Split(line, "= ")[1])
-			if err != nil {
-				panic(err) // serious error parser will not be usable after this
+			sentenceID, e := strconv.Atoi(strings.TrimSpace(line[len("# sent_id ="):]))
+			if e != nil {
+				return nil, &FormatError{Line: line, Err: e}
 			}
 			sentence = &Sentence{ID: sentenceID}
+		case strings.HasPrefix(line, "#"):
+		case sentence == nil:
+			return nil, ErrNoSentence
+		default: // parse token line
+			token, e := ParseToken(line)
+			if e != nil {
+				return nil, &FormatError{Line: line, Err: e}
+			}
+			token.Lemma = strings.ToLower(token.Lemma)
+			sentence.AddToken(token)
 		}
 	}
-	// check integrity
-	if L != LL {
-		panic(fmt.Errorf("Input length %d does not match output length %d ", L, LL))
+	if err = scanner.Err(); err == nil {
+		err = io.ErrUnexpectedEOF
 	}
-	return
+	return nil, err
+}
+
+// resync sends a marker document to udpipe and skips output up to the
+// end of the marker sentence, dropping whatever is left of bad document.
+func (p *Parser) resync() error {
+	marker := p.marker()
+	if _, e := p.proc.stdin.Write([]byte(marker + "\n\n")); e != nil {
+		return e
+	}
+	scanner := p.proc.scanner
+	found := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if found && len(line) == 0 {
+			return nil
+		}
+		if line == "# text = "+marker {
+			found = true
+		}
+	}
+	if e := scanner.Err(); e != nil {
+		return e
+	}
+	return io.ErrUnexpectedEOF
 }
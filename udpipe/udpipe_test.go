@@ -1,12 +1,240 @@
 package udpipe
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testParser starts parser configured by UDPIPE_BIN and UDPIPE_MODEL
+// environment variables, the test is skipped if udpipe is not available.
+func testParser(t *testing.T) *Parser {
+	config := ParserConfig{
+		Binary: os.Getenv("UDPIPE_BIN"),
+		Model:  os.Getenv("UDPIPE_MODEL"),
+		Dir:    "..",
+	}
+	for _, fsPath := range []string{config.binary(), config.model()} {
+		if !filepath.IsAbs(fsPath) {
+			fsPath = filepath.Join(config.Dir, fsPath)
+		}
+		if _, e := os.Stat(fsPath); e != nil {
+			t.Skipf("udpipe is not available: %v", e)
+		}
+	}
+	parser := NewParser(config)
+	if e := parser.Start(); e != nil {
+		t.Fatal(e)
+	}
+	return parser
+}
 
 func TestUdpipe(t *testing.T) {
+	parser := testParser(t)
+	defer parser.Close()
 	text := "Bob brings pizza to Alice."
-	result, e := Parse(text)
-	if e!=nil{
+	result, e := parser.Parse(text)
+	if e != nil {
 		t.Fatal(e)
 	}
 	t.Logf("Result: [%#v]\n", result)
 }
+
+func TestParserPool(t *testing.T) {
+	pool := NewParserPool(fakeConfig(t), 3)
+	if e := pool.Start(); e != nil {
+		t.Fatal(e)
+	}
+	defer pool.Close()
+	var w sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		w.Add(1)
+		go func(i int) {
+			defer w.Done()
+			text := fmt.Sprintf("Text number %d. Second sentence", i)
+			sentences, e := pool.Parse(text)
+			if e != nil {
+				t.Error(e)
+				return
+			}
+			if len(sentences) != 2 || sentences[0].Tokens[2].Word != fmt.Sprintf("%d.", i) {
+				t.Errorf("unexpected parse of %q: %v", text, sentences)
+			}
+		}(i)
+	}
+	w.Wait()
+}
+
+func TestParserResync(t *testing.T) {
+	parser := NewParser(fakeConfig(t))
+	if e := parser.Start(); e != nil {
+		t.Fatal(e)
+	}
+	defer parser.Close()
+	for _, c := range []struct {
+		text string
+		err  interface{}
+	}{
+		{"first line", nil},
+		{"bad BADID here. and more", &FormatError{}},
+		{"second line", nil},
+		{"bad BADLEN", &LengthError{}},
+		{"third line", nil},
+		{"short SHORTLEN. and more", &LengthError{}},
+		{"fourth line", nil},
+	} {
+		sentences, e := parser.Parse(c.text)
+		if c.err == nil {
+			if e != nil {
+				t.Fatalf("%q: %v", c.text, e)
+			}
+			if len(sentences) != 1 || sentences[0].Body != c.text {
+				t.Fatalf("%q: parser is out of sync, got %v", c.text, sentences)
+			}
+			continue
+		}
+		if reflect.TypeOf(e) != reflect.TypeOf(c.err) {
+			t.Fatalf("%q: expected %T got %v", c.text, c.err, e)
+		}
+	}
+}
+
+func TestParserOffsets(t *testing.T) {
+	parser := NewParser(fakeConfig(t))
+	if e := parser.Start(); e != nil {
+		t.Fatal(e)
+	}
+	defer parser.Close()
+	text := "Ёж  ест. Кот\tспит"
+	sentences, e := parser.Parse(text)
+	if e != nil {
+		t.Fatal(e)
+	}
+	runes := []rune(text)
+	var spans [][2]int
+	for _, sentence := range sentences {
+		for _, token := range sentence.Tokens {
+			spans = append(spans, [2]int{token.Start, token.End})
+			if string(runes[token.Start:token.End]) != token.Word {
+				t.Errorf("%q is at %d:%d", token.Word, token.Start, token.End)
+			}
+		}
+	}
+	if expected := [][2]int{{0, 2}, {4, 8}, {9, 12}, {13, 17}}; !reflect.DeepEqual(spans, expected) {
+		t.Errorf("expected %v got %v", expected, spans)
+	}
+	if detokenized := Detokenize(sentences); detokenized != "Ёж ест. Кот спит" {
+		t.Errorf("unexpected detokenization %q", detokenized)
+	}
+}
+
+func TestDetokenize(t *testing.T) {
+	sentence := &Sentence{}
+	for _, line := range []string{
+		"1\tVamos\tir\tVERB\t_\t_\t0\troot\t_\t_",
+		"2-3\tdel\t_\t_\t_\t_\t_\t_\t_\tTokenRange=6:9",
+		"2\tde\tde\tADP\t_\t_\t4\tcase\t_\t_",
+		"3\tel\tel\tDET\t_\t_\t4\tdet\t_\t_",
+		"4\tmar\tmar\tNOUN\t_\t_\t1\tobl\t_\tSpaceAfter=No",
+		"5\t.\t.\tPUNCT\t_\t_\t1\tpunct\t_\t_",
+	} {
+		token, e := ParseToken(line)
+		if e != nil {
+			t.Fatal(e)
+		}
+		sentence.AddToken(token)
+	}
+	if multiword := sentence.Multiwords[0]; multiword.Start != 6 || multiword.End != 9 {
+		t.Errorf("unexpected token range %d:%d", multiword.Start, multiword.End)
+	}
+	if text := sentence.Detokenize(); text != "Vamos del mar." {
+		t.Errorf("unexpected detokenization %q", text)
+	}
+	Align("Vamos del  mar.", []*Sentence{sentence})
+	if de := sentence.Tokens[1]; de.Start != 6 || de.End != 9 || de.SpaceAfter {
+		t.Errorf("unexpected alignment of multiword part %+v", de)
+	}
+	if mar := sentence.Tokens[3]; mar.Start != 11 || mar.End != 14 {
+		t.Errorf("unexpected alignment of %q: %d:%d", mar.Word, mar.Start, mar.End)
+	}
+}
+
+func TestParserRestart(t *testing.T) {
+	config := fakeConfig(t)
+	os.Setenv(fakeCrashEnv, filepath.Join(t.TempDir(), "crashed"))
+	defer os.Unsetenv(fakeCrashEnv)
+	parser := NewParser(config)
+	if e := parser.Start(); e != nil {
+		t.Fatal(e)
+	}
+	sentences, e := parser.Parse("retried CRASHONCE")
+	if e != nil || len(sentences) != 1 || sentences[0].Body != "retried CRASHONCE" {
+		t.Fatalf("expected document to be retried, got %v %v", sentences, e)
+	}
+	if _, e = parser.Parse("always CRASH"); e == nil {
+		t.Fatal("expected crash")
+	} else if exit, ok := e.(*ExitError); !ok || exit.Status == nil {
+		t.Fatalf("expected exit error with status, got %v", e)
+	}
+	if parser.Restarts != 3 {
+		t.Errorf("expected 3 restarts got %d", parser.Restarts)
+	}
+	if sentences, e = parser.Parse("after crash"); e != nil || len(sentences) != 1 {
+		t.Fatalf("parser is not restarted: %v %v", sentences, e)
+	}
+	if e = parser.Close(); e != nil {
+		t.Errorf("unexpected exit %v", e)
+	}
+}
+
+func TestParserNotLoaded(t *testing.T) {
+	config := fakeConfig(t)
+	config.Model = "missing.udpipe"
+	parser := NewParser(config)
+	e := parser.Start()
+	if exit, ok := e.(*ExitError); !ok || exit.Err != errNotLoaded || exit.Status == nil {
+		t.Fatalf("expected model load failure, got %v", e)
+	}
+	if e = parser.Close(); e != nil {
+		t.Error(e)
+	}
+}
+
+func TestParserLoadTimeout(t *testing.T) {
+	config := fakeConfig(t)
+	config.Model = "hang.udpipe"
+	config.LoadTimeout = 100 * time.Millisecond
+	parser := NewParser(config)
+	e := parser.Start()
+	if exit, ok := e.(*ExitError); !ok || exit.Err != errLoadTimeout || exit.Status == nil {
+		t.Fatalf("expected model load timeout, got %v", e)
+	}
+	if e = parser.Close(); e != nil {
+		t.Error(e)
+	}
+}
+
+func TestParserTimeout(t *testing.T) {
+	parser := NewParser(fakeConfig(t))
+	if e := parser.Start(); e != nil {
+		t.Fatal(e)
+	}
+	defer parser.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, e := parser.ParseContext(ctx, "please HANG"); !errors.Is(e, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", e)
+	}
+	if parser.Restarts != 1 {
+		t.Errorf("expected 1 restart got %d", parser.Restarts)
+	}
+	if sentences, e := parser.Parse("after timeout"); e != nil || len(sentences) != 1 {
+		t.Fatalf("parser is not restarted: %v %v", sentences, e)
+	}
+}
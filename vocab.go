@@ -0,0 +1,79 @@
+package gorpora
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/vseledkin/gorpora/tokenizer"
+	"github.com/vseledkin/gorpora/vocab"
+)
+
+// VocabOptions configure Vocab.
+type VocabOptions struct {
+	// N is the maximum n-gram length, 1 counts single tokens.
+	N int
+	// MinCount is the minimum count of output n-grams.
+	MinCount int
+	// Top limits output to the most frequent n-grams, all are output if
+	// it is zero.
+	Top int
+	// MaxEntries limits the number of distinct n-grams counted in memory,
+	// the rest are spilled to files in Dir.
+	MaxEntries int
+	Dir        string
+	// Tokenize splits input with native word tokenizer, otherwise input
+	// tokens are separated by spaces.
+	Tokenize bool
+}
+
+// Vocab counts n-grams of input lines and outputs them with their counts
+// separated by tab, the most frequent first.
+func Vocab(options *VocabOptions) error {
+	counter := vocab.NewCounter(options.N, options.MaxEntries, options.Dir)
+	defer counter.Close()
+	words := tokenizer.NewWordTokenizer()
+	reader := bufio.NewReader(os.Stdin)
+	var tokens []string
+	for {
+		line, err := reader.ReadString('\n')
+		if options.Tokenize {
+			tokens = tokens[:0]
+			words.Each(line, func(token string, start, end int) {
+				tokens = append(tokens, unbreakable(token))
+			})
+		} else {
+			tokens = fields(line)
+		}
+		if e := counter.Add(tokens); e != nil {
+			return e
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	out := bufio.NewWriter(os.Stdout)
+	write := func(ngram string, count int) error {
+		out.WriteString(ngram)
+		out.WriteString("\t")
+		out.WriteString(strconv.Itoa(count))
+		_, e := out.WriteString("\n")
+		return e
+	}
+	if options.Top > 0 {
+		entries, e := counter.Top(options.MinCount, options.Top)
+		if e != nil {
+			return e
+		}
+		for _, entry := range entries {
+			write(entry.Ngram, entry.Count)
+		}
+	} else if e := counter.EachByFrequency(options.MinCount, write); e != nil {
+		return e
+	}
+	return out.Flush()
+}
@@ -0,0 +1,381 @@
+// Package vocab counts tokens and n-grams of tokens in memory bounded
+// by the number of distinct entries, counts exceeding the bound are
+// spilled to sorted temporary files and merged back when read.
+package vocab
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// fanIn is the maximum number of files merged at once, more files are
+// merged in several passes.
+var fanIn = 64
+
+// Counter counts n-grams of one to N tokens, tokens of n-gram are joined
+// with space.
+type Counter struct {
+	// N is the maximum n-gram length.
+	N int
+	// MaxEntries limits the number of distinct n-grams kept in memory,
+	// there is no limit if it is zero.
+	MaxEntries int
+	// Dir is the directory of spill files, default temporary directory
+	// if it is empty.
+	Dir    string
+	counts map[string]int
+	spills []string
+}
+
+// NewCounter returns counter of n-grams up to n tokens long keeping at
+// most maxEntries distinct n-grams in memory.
+func NewCounter(n, maxEntries int, dir string) *Counter {
+	if n < 1 {
+		n = 1
+	}
+	return &Counter{N: n, MaxEntries: maxEntries, Dir: dir, counts: make(map[string]int)}
+}
+
+// Add counts all n-grams of tokens.
+func (c *Counter) Add(tokens []string) error {
+	for i := range tokens {
+		for n := 1; n <= c.N && i+n <= len(tokens); n++ {
+			c.counts[strings.Join(tokens[i:i+n], " ")]++
+		}
+	}
+	if c.MaxEntries > 0 && len(c.counts) >= c.MaxEntries {
+		return c.spill()
+	}
+	return nil
+}
+
+// sorted returns in memory n-grams sorted.
+func (c *Counter) sorted() []string {
+	keys := make([]string, 0, len(c.counts))
+	for key := range c.counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// spill writes in memory counts to a sorted file and clears them.
+func (c *Counter) spill() error {
+	keys := c.sorted()
+	name, e := c.write(func(f func(ngram string, count int) error) error {
+		for _, key := range keys {
+			if e := f(key, c.counts[key]); e != nil {
+				return e
+			}
+		}
+		return nil
+	})
+	if e != nil {
+		return e
+	}
+	c.spills = append(c.spills, name)
+	c.counts = make(map[string]int)
+	return nil
+}
+
+// write writes entries produced by each to a new temporary file and
+// returns its name.
+func (c *Counter) write(each func(f func(ngram string, count int) error) error) (string, error) {
+	f, e := ioutil.TempFile(c.Dir, "gorpora-vocab-")
+	if e != nil {
+		return "", e
+	}
+	w := bufio.NewWriter(f)
+	e = each(func(ngram string, count int) error {
+		w.WriteString(ngram)
+		w.WriteString("\t")
+		w.WriteString(strconv.Itoa(count))
+		_, e := w.WriteString("\n")
+		return e
+	})
+	if e == nil {
+		e = w.Flush()
+	}
+	if ce := f.Close(); e == nil {
+		e = ce
+	}
+	if e != nil {
+		os.Remove(f.Name())
+		return "", e
+	}
+	return f.Name(), nil
+}
+
+// source is a sorted stream of counted n-grams.
+type source struct {
+	next func() bool
+	Entry
+}
+
+// open returns source reading file written by write, read errors are
+// stored to err.
+func open(name string, err *error) (*source, *os.File, error) {
+	file, e := os.Open(name)
+	if e != nil {
+		return nil, nil, e
+	}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	s := &source{}
+	s.next = func() bool {
+		if !scanner.Scan() {
+			if e := scanner.Err(); e != nil && *err == nil {
+				*err = e
+			}
+			return false
+		}
+		line := scanner.Text()
+		tab := strings.LastIndexByte(line, '\t')
+		count, e := strconv.Atoi(line[tab+1:])
+		if tab < 0 || e != nil {
+			if *err == nil {
+				*err = fmt.Errorf("vocab: bad spill line %q in %s", line, name)
+			}
+			return false
+		}
+		s.Ngram, s.Count = line[:tab], count
+		return true
+	}
+	return s, file, nil
+}
+
+// sources is a heap of sources ordered by their current entry.
+type sources struct {
+	s    []*source
+	less func(a, b *Entry) bool
+}
+
+func (s *sources) Len() int           { return len(s.s) }
+func (s *sources) Less(i, j int) bool { return s.less(&s.s[i].Entry, &s.s[j].Entry) }
+func (s *sources) Swap(i, j int)      { s.s[i], s.s[j] = s.s[j], s.s[i] }
+func (s *sources) Push(x interface{}) { s.s = append(s.s, x.(*source)) }
+func (s *sources) Pop() interface{} {
+	old := s.s
+	x := old[len(old)-1]
+	s.s = old[:len(old)-1]
+	return x
+}
+
+// ngramOrder orders entries by n-gram.
+func ngramOrder(a, b *Entry) bool { return a.Ngram < b.Ngram }
+
+// frequencyOrder orders entries by count descending and n-gram ascending.
+func frequencyOrder(a, b *Entry) bool {
+	return a.Count > b.Count || a.Count == b.Count && a.Ngram < b.Ngram
+}
+
+// merge merges files and optional in memory source sorted in less order
+// calling f for every entry, counts of equal n-grams are summed if
+// combine is true.
+func merge(names []string, memory *source, less func(a, b *Entry) bool, combine bool, f func(ngram string, count int) error) (err error) {
+	h := &sources{less: less}
+	if memory != nil && memory.next() {
+		h.s = append(h.s, memory)
+	}
+	for _, name := range names {
+		s, file, e := open(name, &err)
+		if e != nil {
+			return e
+		}
+		defer file.Close()
+		if s.next() {
+			h.s = append(h.s, s)
+		}
+	}
+	heap.Init(h)
+	for h.Len() > 0 && err == nil {
+		entry := h.s[0].Entry
+		for first := true; h.Len() > 0 && (first || combine && h.s[0].Ngram == entry.Ngram); first = false {
+			if !first {
+				entry.Count += h.s[0].Count
+			}
+			if h.s[0].next() {
+				heap.Fix(h, 0)
+			} else {
+				heap.Pop(h)
+			}
+		}
+		if e := f(entry.Ngram, entry.Count); e != nil {
+			return e
+		}
+	}
+	return err
+}
+
+// reduce merges files in passes of at most fanIn files until no more
+// than fanIn are left, merged files are removed.
+func (c *Counter) reduce(names *[]string, less func(a, b *Entry) bool, combine bool) error {
+	for len(*names) > fanIn {
+		batch := (*names)[:fanIn]
+		name, e := c.write(func(f func(ngram string, count int) error) error {
+			return merge(batch, nil, less, combine, f)
+		})
+		if e != nil {
+			return e
+		}
+		for _, merged := range batch {
+			os.Remove(merged)
+		}
+		*names = append((*names)[fanIn:], name)
+	}
+	return nil
+}
+
+// Each calls f for every n-gram counted at least minCount times in
+// n-gram order, in memory counts and spill files are merged.
+func (c *Counter) Each(minCount int, f func(ngram string, count int) error) error {
+	if e := c.reduce(&c.spills, ngramOrder, true); e != nil {
+		return e
+	}
+	keys := c.sorted()
+	memory := &source{}
+	memory.next = func() bool {
+		if len(keys) == 0 {
+			return false
+		}
+		memory.Ngram, memory.Count = keys[0], c.counts[keys[0]]
+		keys = keys[1:]
+		return true
+	}
+	return merge(c.spills, memory, ngramOrder, true, func(ngram string, count int) error {
+		if count < minCount {
+			return nil
+		}
+		return f(ngram, count)
+	})
+}
+
+// EachByFrequency calls f for every n-gram counted at least minCount
+// times, the most frequent first. With MaxEntries n-grams are sorted on
+// disk in runs of at most MaxEntries.
+func (c *Counter) EachByFrequency(minCount int, f func(ngram string, count int) error) (err error) {
+	var runs []string
+	defer func() {
+		for _, name := range runs {
+			os.Remove(name)
+		}
+	}()
+	var entries []Entry
+	flush := func() error {
+		sort.Slice(entries, func(i, j int) bool { return frequencyOrder(&entries[i], &entries[j]) })
+		if c.MaxEntries <= 0 {
+			return nil
+		}
+		name, e := c.write(func(f func(ngram string, count int) error) error {
+			for _, entry := range entries {
+				if e := f(entry.Ngram, entry.Count); e != nil {
+					return e
+				}
+			}
+			return nil
+		})
+		if e != nil {
+			return e
+		}
+		runs = append(runs, name)
+		entries = entries[:0]
+		return nil
+	}
+	e := c.Each(minCount, func(ngram string, count int) error {
+		entries = append(entries, Entry{ngram, count})
+		if c.MaxEntries > 0 && len(entries) >= c.MaxEntries {
+			return flush()
+		}
+		return nil
+	})
+	if e == nil {
+		e = flush()
+	}
+	if e != nil {
+		return e
+	}
+	if c.MaxEntries <= 0 {
+		for _, entry := range entries {
+			if e := f(entry.Ngram, entry.Count); e != nil {
+				return e
+			}
+		}
+		return nil
+	}
+	if e := c.reduce(&runs, frequencyOrder, false); e != nil {
+		return e
+	}
+	return merge(runs, nil, frequencyOrder, false, f)
+}
+
+// Close removes spill files.
+func (c *Counter) Close() error {
+	var err error
+	for _, name := range c.spills {
+		if e := os.Remove(name); e != nil && err == nil {
+			err = e
+		}
+	}
+	c.spills = nil
+	return err
+}
+
+// Entry is an n-gram and its count.
+type Entry struct {
+	Ngram string
+	Count int
+}
+
+// lowest is a heap of entries with the least frequent on top.
+type lowest []Entry
+
+func (e lowest) Len() int            { return len(e) }
+func (e lowest) Less(i, j int) bool  { return frequencyOrder(&e[j], &e[i]) }
+func (e lowest) Swap(i, j int)       { e[i], e[j] = e[j], e[i] }
+func (e *lowest) Push(x interface{}) { *e = append(*e, x.(Entry)) }
+func (e *lowest) Pop() interface{} {
+	old := *e
+	x := old[len(old)-1]
+	*e = old[:len(old)-1]
+	return x
+}
+
+// Top returns n-grams counted at least minCount times sorted by
+// frequency, only top most frequent are kept if top is positive. Use
+// EachByFrequency to output all n-grams in bounded memory.
+func (c *Counter) Top(minCount, top int) ([]Entry, error) {
+	var entries []Entry
+	if top <= 0 {
+		e := c.EachByFrequency(minCount, func(ngram string, count int) error {
+			entries = append(entries, Entry{ngram, count})
+			return nil
+		})
+		if e != nil {
+			return nil, e
+		}
+		return entries, nil
+	}
+	h := (*lowest)(&entries)
+	e := c.Each(minCount, func(ngram string, count int) error {
+		entry := Entry{ngram, count}
+		switch {
+		case len(entries) < top:
+			heap.Push(h, entry)
+		case frequencyOrder(&entry, &entries[0]):
+			entries[0] = entry
+			heap.Fix(h, 0)
+		}
+		return nil
+	})
+	if e != nil {
+		return nil, e
+	}
+	sort.Slice(entries, func(i, j int) bool { return frequencyOrder(&entries[i], &entries[j]) })
+	return entries, nil
+}
@@ -0,0 +1,74 @@
+package vocab
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func count(t *testing.T, maxEntries, minCount, top int) []Entry {
+	counter := NewCounter(2, maxEntries, t.TempDir())
+	defer counter.Close()
+	for _, line := range []string{"a b a b c", "b c d", "a b", "e"} {
+		if e := counter.Add(strings.Fields(line)); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if maxEntries > 0 && len(counter.spills) == 0 {
+		t.Fatal("expected counts to be spilled")
+	}
+	entries, e := counter.Top(minCount, top)
+	if e != nil {
+		t.Fatal(e)
+	}
+	return entries
+}
+
+func TestCounter(t *testing.T) {
+	expected := []Entry{{"b", 4}, {"a", 3}, {"a b", 3}, {"b c", 2}, {"c", 2}}
+	for _, maxEntries := range []int{0, 1, 3} {
+		if entries := count(t, maxEntries, 2, 0); !reflect.DeepEqual(entries, expected) {
+			t.Errorf("max entries %d: expected %v got %v", maxEntries, expected, entries)
+		}
+		if entries := count(t, maxEntries, 1, 3); !reflect.DeepEqual(entries, expected[:3]) {
+			t.Errorf("max entries %d: expected top %v got %v", maxEntries, expected[:3], entries)
+		}
+	}
+}
+
+// addLetters adds lines of x followed by runs of letters to counter.
+func addLetters(t *testing.T, counter *Counter) {
+	for i := 0; i < 40; i++ {
+		tokens := []string{"x"}
+		for j := 0; j <= i%5; j++ {
+			tokens = append(tokens, string(rune('a'+i%7+j)))
+		}
+		if e := counter.Add(tokens); e != nil {
+			t.Fatal(e)
+		}
+	}
+}
+
+func TestCounterMergePasses(t *testing.T) {
+	defer func(n int) { fanIn = n }(fanIn)
+	fanIn = 3
+	counter := NewCounter(1, 2, t.TempDir())
+	defer counter.Close()
+	addLetters(t, counter)
+	if len(counter.spills) <= fanIn {
+		t.Fatalf("expected more than %d spills got %d", fanIn, len(counter.spills))
+	}
+	entries, e := counter.Top(1, 0)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(counter.spills) > fanIn {
+		t.Errorf("expected at most %d spills after merge got %d", fanIn, len(counter.spills))
+	}
+	unbounded := NewCounter(1, 0, "")
+	addLetters(t, unbounded)
+	expected, _ := unbounded.Top(1, 0)
+	if !reflect.DeepEqual(entries, expected) || entries[0] != (Entry{"x", 40}) {
+		t.Errorf("expected %v got %v", expected, entries)
+	}
+}